@@ -0,0 +1,99 @@
+// Package address 解析 Benfen 地址，支持 BFC 前缀校验和格式与 0x 十六进制格式
+package address
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 地址格式参数
+const (
+	// Length 地址字节长度
+	Length = 32
+	// bfcPrefix BFC 格式前缀
+	bfcPrefix = "BFC"
+	// checksumLength 校验和的十六进制字符数
+	checksumLength = 4
+)
+
+var (
+	// ErrInvalidFormat 既不是 BFC 格式也不是 0x 格式
+	ErrInvalidFormat = errors.New("地址必须以 BFC 或 0x 开头")
+	// ErrInvalidChecksum BFC 地址校验和不匹配
+	ErrInvalidChecksum = errors.New("BFC 地址校验和不匹配")
+)
+
+// Address 32 字节账户或对象地址
+type Address [Length]byte
+
+// Parse 解析 BFC 格式或 0x 格式的地址。
+// BFC 格式为 "BFC" + 64 位十六进制 + 4 位校验和，校验和为十六进制字符串 sha256 的前两个字节；
+// 0x 格式允许省略前导零。
+func Parse(text string) (Address, error) {
+	var addr Address
+	text = strings.TrimSpace(text)
+
+	switch {
+	case strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X"):
+		body := strings.ToLower(text[2:])
+		if body == "" || len(body) > Length*2 {
+			return addr, fmt.Errorf("0x 地址长度无效: %d 位十六进制", len(body))
+		}
+		body = strings.Repeat("0", Length*2-len(body)) + body
+		return decode(body)
+
+	case strings.HasPrefix(strings.ToUpper(text), bfcPrefix):
+		body := strings.ToLower(text[len(bfcPrefix):])
+		if len(body) != Length*2+checksumLength {
+			return addr, fmt.Errorf("BFC 地址长度无效: 应为 %d 位十六进制，实际 %d", Length*2+checksumLength, len(body))
+		}
+		hexPart, sum := body[:Length*2], body[Length*2:]
+		addr, err := decode(hexPart)
+		if err != nil {
+			return addr, err
+		}
+		if checksum(hexPart) != sum {
+			return addr, ErrInvalidChecksum
+		}
+		return addr, nil
+
+	default:
+		return addr, ErrInvalidFormat
+	}
+}
+
+// decode 解析 64 位十六进制
+func decode(body string) (Address, error) {
+	var addr Address
+	raw, err := hex.DecodeString(body)
+	if err != nil {
+		return addr, fmt.Errorf("地址包含非十六进制字符: %v", err)
+	}
+	copy(addr[:], raw)
+	return addr, nil
+}
+
+// checksum 计算 BFC 地址校验和
+func checksum(hexPart string) string {
+	sum := sha256.Sum256([]byte(hexPart))
+	return hex.EncodeToString(sum[:])[:checksumLength]
+}
+
+// Hex 返回规范的 0x 十六进制格式
+func (a Address) Hex() string {
+	return "0x" + hex.EncodeToString(a[:])
+}
+
+// BFC 返回带校验和的 BFC 格式
+func (a Address) BFC() string {
+	hexPart := hex.EncodeToString(a[:])
+	return bfcPrefix + hexPart + checksum(hexPart)
+}
+
+// String 返回规范的 0x 十六进制格式
+func (a Address) String() string {
+	return a.Hex()
+}
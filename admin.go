@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// isAdminRequest 判断请求是否携带有效的管理接口令牌
+func isAdminRequest(r *http.Request) bool {
+	token := GetAdminToken()
+	provided := r.Header.Get("X-Admin-Token")
+	return token != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(token)) == 1
+}
+
+// AdminAuthMiddleware 校验管理接口令牌
+func AdminAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminRequest(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			response := TokenResponse{
+				Success: false,
+				Message: "无权访问管理接口",
+			}
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rpcEndpointsStatus 按网络返回所有 RPC 节点的健康状态
+func rpcEndpointsStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	status := make(map[string]interface{}, len(networks))
+	for name, network := range networks {
+		status[name] = map[string]interface{}{
+			"chain_id":  network.Config.ChainID,
+			"default":   name == GetDefaultNetwork(),
+			"endpoints": network.Client.Endpoints(),
+		}
+	}
+	response := TokenResponse{
+		Success: true,
+		Message: "获取节点状态成功",
+		Data:    status,
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// signAndPublishToken 使用服务端密钥构建、签名并执行发布交易
+func signAndPublishToken(w http.ResponseWriter, r *http.Request) {
+	if serverSigner == nil {
+		writeResponse(w, http.StatusForbidden, TokenResponse{
+			Success: false,
+			Message: errSignerDisabled.Error(),
+		})
+		return
+	}
+
+	// 解析请求体，发送者固定为服务端账户
+	var req PublishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+	req.Sender = serverSigner.Address()
+
+	if req.CompileID != "" {
+		record, status, err := publishableRecord(req.CompileID)
+		if err == nil && !sameModules(req.CompiledModules, record.Modules) {
+			status, err = http.StatusConflict, fmt.Errorf("compiled_modules 与编译记录 %s 不一致", req.CompileID)
+		}
+		if err != nil {
+			writeResponse(w, status, TokenResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	r, ok := selectCompileNetwork(w, r, req.Network, req.CompileID)
+	if !ok {
+		return
+	}
+
+	if _, err := parseGasBudget(r.Context(), req.GasBudget); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	publish, err := buildPublishTransaction(r.Context(), req)
+	if err != nil {
+		writePublishError(w, err)
+		return
+	}
+
+	result, err := signAndExecute(r.Context(), publish, TxKindPublish, req.CompileID)
+	if err != nil {
+		writeRPCError(w, "执行发布交易失败", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"sender":    req.Sender,
+		"publish":   publish,
+		"execution": result,
+	}
+	if result.Status != "success" {
+		writeResponse(w, http.StatusUnprocessableEntity, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("发布交易执行失败: %s", result.Error),
+			Data:    data,
+		})
+		return
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "发布交易执行成功",
+		Data:    data,
+	})
+}
+
+// sameModules 判断请求中的模块与编译记录的模块是否完全一致
+func sameModules(modules []interface{}, recorded []string) bool {
+	if len(modules) != len(recorded) {
+		return false
+	}
+	for i, module := range modules {
+		if text, ok := module.(string); !ok || text != recorded[i] {
+			return false
+		}
+	}
+	return true
+}
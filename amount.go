@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"obc_coin_api/benfen"
+)
+
+// errCoinMetadataNotFound 节点上不存在该代币的元数据
+var errCoinMetadataNotFound = errors.New("代币元数据不存在")
+
+// parseAmount 将带小数的可读数量按精度换算为最小单位，小数位超过精度或超出 u64 时报错
+func parseAmount(text string, decimals uint8) (uint64, error) {
+	text = strings.TrimSpace(text)
+	whole, frac, _ := strings.Cut(text, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("数量不能为空")
+	}
+	if strings.Trim(whole+frac, "0123456789") != "" {
+		return 0, fmt.Errorf("数量格式无效: %s", text)
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > int(decimals) {
+		return 0, fmt.Errorf("数量 %s 的小数位超过代币精度 %d", text, decimals)
+	}
+
+	digits := whole + frac + strings.Repeat("0", int(decimals)-len(frac))
+	value, ok := new(big.Int).SetString(digits, 10)
+	if !ok {
+		return 0, fmt.Errorf("数量格式无效: %s", text)
+	}
+	if value.Sign() == 0 {
+		return 0, fmt.Errorf("数量必须大于 0")
+	}
+	if !value.IsUint64() {
+		return 0, fmt.Errorf("数量 %s 超出范围", text)
+	}
+	return value.Uint64(), nil
+}
+
+// formatAmount 将最小单位数量按精度格式化为可读数量，去掉末尾多余的 0
+func formatAmount(value uint64, decimals uint8) string {
+	text := fmt.Sprintf("%0*d", int(decimals)+1, value)
+	if decimals == 0 {
+		return text
+	}
+	point := len(text) - int(decimals)
+	frac := strings.TrimRight(text[point:], "0")
+	if frac == "" {
+		return text[:point]
+	}
+	return text[:point] + "." + frac
+}
+
+// addAmounts 累加最小单位数量，溢出时报错
+func addAmounts(a, b uint64) (uint64, error) {
+	if a > math.MaxUint64-b {
+		return 0, fmt.Errorf("数量合计超出范围")
+	}
+	return a + b, nil
+}
+
+// fetchCoinMetadata 查询代币元数据
+func fetchCoinMetadata(ctx context.Context, coinType string) (*benfen.CoinMetadata, error) {
+	var metadata *benfen.CoinMetadata
+	if err := rpcFor(ctx).Call(ctx, benfen.MethodGetCoinMetadata, &metadata, coinType); err != nil {
+		return nil, err
+	}
+	if metadata == nil {
+		return nil, fmt.Errorf("%w: %s", errCoinMetadataNotFound, coinType)
+	}
+	return metadata, nil
+}
+
+// fetchTotalSupply 查询代币总供应量
+func fetchTotalSupply(ctx context.Context, coinType string) (uint64, error) {
+	var supply benfen.Supply
+	if err := rpcFor(ctx).Call(ctx, benfen.MethodGetTotalSupply, &supply, coinType); err != nil {
+		return 0, err
+	}
+	return uint64(supply.Value), nil
+}
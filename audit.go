@@ -0,0 +1,448 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// 审计记录的请求结果
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// 请求方身份
+const (
+	AuditActorAdmin     = "admin"
+	AuditActorAnonymous = "anonymous"
+)
+
+// AuditMACHMAC 记录哈希使用 HMAC-SHA256 计算
+const AuditMACHMAC = "hmac-sha256"
+
+// maxAuditBodyBytes 审计中间件读取的请求体上限，与模拟节点的请求上限一致
+const maxAuditBodyBytes = 16 << 20
+
+// auditGenesisHash 第一条记录的 prev_hash
+var auditGenesisHash = strings.Repeat("0", 64)
+
+// AuditRecord 一次写操作请求的审计记录。
+// Hash 为除 Hash 外全部字段的 JSON 的哈希，其中包含上一条记录的 Hash，
+// 修改、删除或插入任何一条记录都会使链校验失败。
+// MAC 为 hmac-sha256 时哈希使用日志文件之外的密钥计算，不知道密钥无法重算整条链
+type AuditRecord struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	// ClientIP 按 X-Real-IP/X-Forwarded-For 得到的客户端 IP，可由客户端伪造；
+	// PeerIP 为实际建立连接的对端地址，经过反向代理时为代理地址
+	ClientIP string `json:"client_ip"`
+	PeerIP   string `json:"peer_ip,omitempty"`
+	Actor    string `json:"actor"`
+	// KeyID 管理令牌 sha256 的前 8 字节，令牌更换后可区分
+	KeyID  string `json:"key_id,omitempty"`
+	Action string `json:"action"`
+	Path   string `json:"path"`
+	// ParamsHash 请求参数的 sha256，见 hashRequestParams
+	ParamsHash string `json:"params_hash"`
+	Status     int    `json:"status"`
+	Outcome    string `json:"outcome"`
+	JobID      string `json:"job_id,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	// Panic 处理请求时发生 panic，状态码记为 500
+	Panic    bool   `json:"panic,omitempty"`
+	MAC      string `json:"mac,omitempty"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// computeHash 计算记录的哈希，MAC 为 hmac-sha256 时使用 key 计算 HMAC
+func (r AuditRecord) computeHash(key []byte) string {
+	r.Hash = ""
+	data, _ := json.Marshal(r)
+	if r.MAC == AuditMACHMAC {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditLog 只追加的审计日志文件，每行一条 JSON 记录
+type auditLog struct {
+	mu   sync.Mutex
+	file *os.File
+	key  []byte
+	seq  uint64
+	head string
+}
+
+// AuditHead 最新一条审计记录的序号和哈希，保存到日志文件之外用于发现末尾记录被截断或整条链被重写
+type AuditHead struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+	MAC  string `json:"mac,omitempty"`
+}
+
+var auditTrail *auditLog
+
+// initAuditLog 打开审计日志，从最后一条记录继续链接
+func initAuditLog() {
+	if !IsAuditEnabled() {
+		log.Printf("审计日志未启用")
+		return
+	}
+	key := []byte(os.Getenv(GetAuditKeyEnv()))
+	if len(key) == 0 {
+		log.Printf("警告: 环境变量 %s 未设置，审计日志哈希链不使用密钥，能改写日志文件的一方可以重算整条链", GetAuditKeyEnv())
+	}
+	trail, err := openAuditLog(GetAuditPath(), key)
+	if err != nil {
+		log.Fatalf("打开审计日志失败: %v", err)
+	}
+	auditTrail = trail
+	head := trail.currentHead()
+	log.Printf("审计日志: %s，已有 %d 条记录，最新哈希 %s", GetAuditPath(), head.Seq, head.Hash)
+	go runAuditAnchor(trail, time.Duration(GetAuditAnchorIntervalSeconds())*time.Second)
+}
+
+// openAuditLog 打开审计日志，读取最后一条记录的序号和哈希，key 为空时不使用 HMAC。
+// 最后一行不完整（如写入时进程崩溃）时拒绝打开，需先用 audit verify 检查
+func openAuditLog(path string, key []byte) (*auditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	l := &auditLog{key: key, head: auditGenesisHash}
+	last, err := lastAuditRecord(path)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		l.seq, l.head = last.Seq, last.Hash
+	}
+	l.file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// lastAuditRecord 读取最后一条记录，文件不存在或为空时返回 nil
+func lastAuditRecord(path string) (*AuditRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var line []byte
+	reader := bufio.NewReader(f)
+	for {
+		next, err := reader.ReadBytes('\n')
+		if len(next) > 0 {
+			line = next
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if line == nil {
+		return nil, nil
+	}
+	var record AuditRecord
+	if !bytes.HasSuffix(line, []byte("\n")) || json.Unmarshal(line, &record) != nil || record.Hash == "" {
+		return nil, fmt.Errorf("审计日志 %s 的最后一行不完整", path)
+	}
+	return &record, nil
+}
+
+// append 链接到上一条记录并写入磁盘
+func (l *auditLog) append(record *AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Seq = l.seq + 1
+	record.PrevHash = l.head
+	record.MAC = ""
+	if len(l.key) > 0 {
+		record.MAC = AuditMACHMAC
+	}
+	record.Hash = record.computeHash(l.key)
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if err := l.file.Sync(); err != nil {
+		return err
+	}
+	l.seq, l.head = record.Seq, record.Hash
+	return nil
+}
+
+// currentHead 最新一条记录的序号和哈希
+func (l *auditLog) currentHead() AuditHead {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	head := AuditHead{Seq: l.seq, Hash: l.head}
+	if len(l.key) > 0 {
+		head.MAC = AuditMACHMAC
+	}
+	return head
+}
+
+// runAuditAnchor 定期把最新哈希写入服务日志，日志转存到其他系统后可作为外部锚点，
+// 与 audit verify -head 配合发现末尾记录被截断或整条链被重写。没有新记录时不重复输出
+func runAuditAnchor(l *auditLog, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var anchored uint64
+	for range ticker.C {
+		head := l.currentHead()
+		if head.Seq == anchored {
+			continue
+		}
+		log.Printf("审计日志锚点: seq=%d hash=%s", head.Seq, head.Hash)
+		anchored = head.Seq
+	}
+}
+
+// auditHead 返回最新一条审计记录的序号和哈希
+func auditHead(w http.ResponseWriter, r *http.Request) {
+	if auditTrail == nil {
+		writeResponse(w, http.StatusNotFound, TokenResponse{
+			Success: false,
+			Message: "审计日志未启用",
+		})
+		return
+	}
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "获取审计日志最新哈希成功",
+		Data:    auditTrail.currentHead(),
+	})
+}
+
+// auditedMethod 只审计写操作，查询请求不记录
+func auditedMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// hashRequestParams 计算请求参数的哈希：sha256(方法 \n 路径 \n 查询串 \n 请求体)。
+// 审计日志不保存参数本身，持有原始请求的一方可以重新计算并比对
+func hashRequestParams(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// auditActor 请求方身份，携带有效管理令牌时返回令牌指纹
+func auditActor(r *http.Request) (string, string) {
+	if isAdminRequest(r) {
+		sum := sha256.Sum256([]byte(GetAdminToken()))
+		return AuditActorAdmin, hex.EncodeToString(sum[:8])
+	}
+	return AuditActorAnonymous, ""
+}
+
+type peerAddrKey struct{}
+
+// PeerAddrMiddleware 保存 TCP 连接的对端地址，需注册在 RealIP 之前，
+// RealIP 会用客户端可伪造的 X-Real-IP/X-Forwarded-For 替换 RemoteAddr
+func PeerAddrMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), peerAddrKey{}, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// clientIP 客户端 IP，RealIP 中间件已按 X-Real-IP/X-Forwarded-For 替换 RemoteAddr
+func clientIP(r *http.Request) string {
+	return hostOnly(r.RemoteAddr)
+}
+
+// peerIP 实际建立连接的对端 IP，未经过 PeerAddrMiddleware 时使用 RemoteAddr
+func peerIP(r *http.Request) string {
+	addr, _ := r.Context().Value(peerAddrKey{}).(string)
+	if addr == "" {
+		addr = r.RemoteAddr
+	}
+	return hostOnly(addr)
+}
+
+// hostOnly 去掉地址中的端口
+func hostOnly(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// AuditMiddleware 为写操作请求追加审计记录，写入失败只记录日志，不影响请求。
+// 记录在 defer 中追加，处理请求时 panic 也会记录（状态码 500），随后继续 panic 交给 Recoverer
+func AuditMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auditTrail == nil || !auditedMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		var body []byte
+		defer func() {
+			recovered := recover()
+			appendAuditRecord(r, ww, body, start, recovered != nil)
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+
+		body, err := io.ReadAll(http.MaxBytesReader(ww, r.Body, maxAuditBodyBytes))
+		r.Body.Close()
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeResponse(ww, http.StatusRequestEntityTooLarge, TokenResponse{
+					Success: false,
+					Message: fmt.Sprintf("请求体超过 %d 字节", tooLarge.Limit),
+				})
+				return
+			}
+			writeResponse(ww, http.StatusBadRequest, TokenResponse{
+				Success: false,
+				Message: fmt.Sprintf("读取请求体失败: %v", err),
+			})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// appendAuditRecord 按请求和响应生成审计记录并追加，panicked 时状态码记为 500
+func appendAuditRecord(r *http.Request, ww middleware.WrapResponseWriter, body []byte, start time.Time, panicked bool) {
+	status := ww.Status()
+	switch {
+	case panicked && status < http.StatusBadRequest:
+		status = http.StatusInternalServerError
+	case status == 0:
+		status = http.StatusOK
+	}
+	outcome := AuditOutcomeSuccess
+	if status >= http.StatusBadRequest {
+		outcome = AuditOutcomeFailure
+	}
+	action := r.Method + " " + r.URL.Path
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		action = r.Method + " " + rctx.RoutePattern()
+	}
+	actor, keyID := auditActor(r)
+
+	record := &AuditRecord{
+		Time:       start.UTC(),
+		RequestID:  middleware.GetReqID(r.Context()),
+		ClientIP:   clientIP(r),
+		PeerIP:     peerIP(r),
+		Actor:      actor,
+		KeyID:      keyID,
+		Action:     action,
+		Path:       r.URL.Path,
+		ParamsHash: hashRequestParams(r, body),
+		Status:     status,
+		Outcome:    outcome,
+		JobID:      ww.Header().Get("X-Job-ID"),
+		DurationMs: time.Since(start).Milliseconds(),
+		Panic:      panicked,
+	}
+	if err := auditTrail.append(record); err != nil {
+		log.Printf("写入审计日志失败 %s %s: %v", record.Action, record.RequestID, err)
+	}
+}
+
+// verifyAuditLog 从头校验审计日志的哈希链，返回记录数和最后一条记录的哈希。
+// 能发现被修改的记录、缺失或插入的记录（序号不连续或 prev_hash 不匹配）以及不完整的行；
+// 末尾记录被整体截断无法从文件本身发现，需与外部保存的最新哈希比对。
+// 提供 key 时要求每条记录都使用 HMAC，防止记录被替换为可重算的 sha256；
+// 记录使用 HMAC 而未提供 key 时无法校验，返回错误
+func verifyAuditLog(path string, key []byte) (uint64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+
+	var seq uint64
+	head := auditGenesisHash
+	reader := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return seq, head, err
+		}
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			return seq, head, fmt.Errorf("第 %d 行不完整", lineNo)
+		}
+
+		var record AuditRecord
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&record); err != nil {
+			return seq, head, fmt.Errorf("第 %d 行无法解析: %v", lineNo, err)
+		}
+		switch {
+		case record.MAC != "" && record.MAC != AuditMACHMAC:
+			return seq, head, fmt.Errorf("第 %d 行（序号 %d）的 mac 为未知算法 %s", lineNo, record.Seq, record.MAC)
+		case record.MAC == AuditMACHMAC && len(key) == 0:
+			return seq, head, fmt.Errorf("第 %d 行（序号 %d）使用 HMAC，校验需要提供密钥", lineNo, record.Seq)
+		case record.MAC == "" && len(key) > 0:
+			return seq, head, fmt.Errorf("第 %d 行（序号 %d）没有使用 HMAC，记录可能被替换为可重算的哈希", lineNo, record.Seq)
+		case record.Seq != seq+1:
+			return seq, head, fmt.Errorf("第 %d 行序号为 %d，应为 %d，记录缺失或顺序被改动", lineNo, record.Seq, seq+1)
+		case record.PrevHash != head:
+			return seq, head, fmt.Errorf("第 %d 行（序号 %d）的 prev_hash 与上一条记录不匹配", lineNo, record.Seq)
+		case record.computeHash(key) != record.Hash:
+			return seq, head, fmt.Errorf("第 %d 行（序号 %d）的哈希不匹配，记录被修改", lineNo, record.Seq)
+		}
+		seq, head = record.Seq, record.Hash
+	}
+	return seq, head, nil
+}
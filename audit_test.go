@@ -0,0 +1,105 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useAuditLog 将审计日志替换为临时文件，测试结束后恢复
+func useAuditLog(t *testing.T, key []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	trail, err := openAuditLog(path, key)
+	if err != nil {
+		t.Fatalf("打开审计日志失败: %v", err)
+	}
+	saved := auditTrail
+	auditTrail = trail
+	t.Cleanup(func() {
+		auditTrail = saved
+		trail.file.Close()
+	})
+	return path
+}
+
+// 处理请求时 panic 也要记录，随后继续 panic 交给 Recoverer
+func TestAuditMiddlewarePanic(t *testing.T) {
+	path := useAuditLog(t, nil)
+	handler := AuditMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("panic 没有继续传递")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/token/add", strings.NewReader("{}")))
+	}()
+
+	last, err := lastAuditRecord(path)
+	if err != nil || last == nil {
+		t.Fatalf("没有审计记录: %v", err)
+	}
+	if !last.Panic || last.Status != http.StatusInternalServerError || last.Outcome != AuditOutcomeFailure {
+		t.Fatalf("panic 请求的审计记录不正确: %+v", last)
+	}
+}
+
+func TestAuditMiddlewareBodyLimit(t *testing.T) {
+	path := useAuditLog(t, nil)
+	called := false
+	handler := AuditMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(strings.Repeat("a", maxAuditBodyBytes+1))
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/token/add", body))
+	if rec.Code != http.StatusRequestEntityTooLarge || called {
+		t.Fatalf("超长请求体应返回 413 且不进入处理函数，实际 %d", rec.Code)
+	}
+	if last, _ := lastAuditRecord(path); last == nil || last.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("超长请求体的审计记录不正确: %+v", last)
+	}
+}
+
+// 使用密钥的链需要密钥才能校验，提供密钥时未使用密钥的记录视为降级
+func TestVerifyAuditLogKey(t *testing.T) {
+	key := []byte("audit-key")
+	path := useAuditLog(t, key)
+	for i := 0; i < 2; i++ {
+		if err := auditTrail.append(&AuditRecord{Action: "POST /api/token/add", Status: http.StatusOK}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, head, err := verifyAuditLog(path, key)
+	if err != nil || count != 2 || head != auditTrail.currentHead().Hash {
+		t.Fatalf("校验失败: count=%d err=%v", count, err)
+	}
+	if _, _, err := verifyAuditLog(path, nil); err == nil {
+		t.Fatalf("没有密钥时不应校验通过")
+	}
+	if _, _, err := verifyAuditLog(path, []byte("other-key")); err == nil {
+		t.Fatalf("密钥错误时不应校验通过")
+	}
+
+	// 不知道密钥的一方用 sha256 重写整条链
+	unkeyed := filepath.Join(t.TempDir(), "audit.log")
+	trail, err := openAuditLog(unkeyed, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer trail.file.Close()
+	if err := trail.append(&AuditRecord{Action: "POST /api/token/add", Status: http.StatusOK}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := verifyAuditLog(unkeyed, key); err == nil || !strings.Contains(err.Error(), "没有使用 HMAC") {
+		t.Fatalf("提供密钥时应拒绝未使用 HMAC 的记录: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/address"
+	"obc_coin_api/benfen"
+)
+
+// TreasuryCapTransferRequest 转移铸币权请求
+type TreasuryCapTransferRequest struct {
+	TokenOpRequest
+	// TreasuryCapID 未指定时使用发布记录中的 TreasuryCap
+	TreasuryCapID     string `json:"treasury_cap_id,omitempty"`
+	Recipient         string `json:"recipient"`
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+}
+
+// MetadataFreezeRequest 冻结元数据请求
+type MetadataFreezeRequest struct {
+	TokenOpRequest
+	// MetadataObjectID 未指定时使用发布记录中的 CoinMetadata
+	MetadataObjectID  string `json:"metadata_object_id,omitempty"`
+	ConfirmationToken string `json:"confirmation_token,omitempty"`
+}
+
+// transferObjectBuilder 返回调用 unsafe_transferObject 构建转移交易的函数
+func transferObjectBuilder(sender, objectID, recipient string) txBuildFunc {
+	return func(ctx context.Context, gas, gasBudget string) (*benfen.TransactionBlockBytes, error) {
+		var tx benfen.TransactionBlockBytes
+		if err := rpcFor(ctx).Call(ctx, benfen.MethodUnsafeTransfer, &tx, sender, objectID, gas, gasBudget, recipient); err != nil {
+			return nil, err
+		}
+		return &tx, nil
+	}
+}
+
+// transferTreasuryCap 构建将 TreasuryCap 转移给新持有者的交易，需要确认令牌
+func transferTreasuryCap(w http.ResponseWriter, r *http.Request) {
+	coinType, err := parseCoinType(chi.URLParam(r, "coinType"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var req TreasuryCapTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+
+	recipient, err := address.Parse(req.Recipient)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("recipient 无效: %v", err),
+		})
+		return
+	}
+	if recipient == (address.Address{}) {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "recipient 不能是零地址",
+		})
+		return
+	}
+	req.Recipient = recipient.Hex()
+
+	r, ok := prepareTokenOp(w, r, &req.TokenOpRequest)
+	if !ok {
+		return
+	}
+	if req.Recipient == req.Sender {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "recipient 与 sender 相同",
+		})
+		return
+	}
+
+	tokenID, ok := resolveTreasuryCap(r.Context(), w, coinType, &req.TreasuryCapID)
+	if !ok {
+		return
+	}
+
+	description := fmt.Sprintf("将 %s 的 TreasuryCap %s 从 %s 转移给 %s，转移后原持有者将失去铸币和销毁权限", coinType, req.TreasuryCapID, req.Sender, req.Recipient)
+	if !requireConfirmation(w, r, req.ConfirmationToken, TxKindCapTransfer, description, req.Sender, coinType, req.TreasuryCapID, req.Recipient) {
+		return
+	}
+
+	runTokenOp(w, r, req.TokenOpRequest, TxKindCapTransfer, tokenID, transferObjectBuilder(req.Sender, req.TreasuryCapID, req.Recipient), map[string]interface{}{
+		"coin_type":       coinType,
+		"treasury_cap_id": req.TreasuryCapID,
+		"recipient":       req.Recipient,
+	})
+}
+
+// freezeMetadata 构建调用 transfer::public_freeze_object 冻结 CoinMetadata 的交易，需要确认令牌
+func freezeMetadata(w http.ResponseWriter, r *http.Request) {
+	coinType, err := parseCoinType(chi.URLParam(r, "coinType"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var req MetadataFreezeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+
+	r, ok := prepareTokenOp(w, r, &req.TokenOpRequest)
+	if !ok {
+		return
+	}
+
+	tokenID, ok := resolveMetadataObject(r.Context(), w, coinType, &req.MetadataObjectID)
+	if !ok {
+		return
+	}
+
+	description := fmt.Sprintf("冻结 %s 的 CoinMetadata %s，冻结后元数据将永久不可修改", coinType, req.MetadataObjectID)
+	if !requireConfirmation(w, r, req.ConfirmationToken, TxKindMetadataFreeze, description, req.Sender, coinType, req.MetadataObjectID) {
+		return
+	}
+
+	call := MoveCall{
+		Package:  "0x2",
+		Module:   "transfer",
+		Function: "public_freeze_object",
+		TypeArgs: []string{fmt.Sprintf("0x2::coin::CoinMetadata<%s>", coinType)},
+		Args:     []interface{}{req.MetadataObjectID},
+	}
+	runTokenOp(w, r, req.TokenOpRequest, TxKindMetadataFreeze, tokenID, moveCallBuilder(req.Sender, call), map[string]interface{}{
+		"coin_type":          coinType,
+		"metadata_object_id": req.MetadataObjectID,
+		"call":               call,
+	})
+}
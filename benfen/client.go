@@ -0,0 +1,214 @@
+package benfen
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// 重试退避参数
+const (
+	retryBaseDelay = 200 * time.Millisecond
+	retryMaxDelay  = 5 * time.Second
+)
+
+// Options 客户端配置
+type Options struct {
+	URLs                []string
+	Timeout             time.Duration
+	RetryCount          int
+	HealthCheckInterval time.Duration
+	MaxCheckpointLag    uint64
+	// Transport 为空时使用 http.DefaultTransport，可替换为 Recorder 或 ReplayTransport
+	Transport http.RoundTripper
+}
+
+// Client Benfen JSON-RPC 客户端，可并发使用
+type Client struct {
+	pool        *pool
+	httpClient  *http.Client
+	retryCount  int
+	healthEvery time.Duration
+	nextID      atomic.Uint64
+}
+
+// NewClient 创建新的 RPC 客户端
+func NewClient(opts Options) *Client {
+	if opts.RetryCount < 0 {
+		opts.RetryCount = 0
+	}
+	return &Client{
+		pool:        newPool(opts.URLs, opts.MaxCheckpointLag),
+		httpClient:  &http.Client{Timeout: opts.Timeout, Transport: opts.Transport},
+		retryCount:  opts.RetryCount,
+		healthEvery: opts.HealthCheckInterval,
+	}
+}
+
+// StartHealthChecks 立即探测一次所有节点，之后按配置间隔定期探测，直到 ctx 结束
+func (c *Client) StartHealthChecks(ctx context.Context) {
+	c.pool.probe(ctx, c)
+	if c.healthEvery <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.healthEvery)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.pool.probe(ctx, c)
+			}
+		}
+	}()
+}
+
+// Endpoints 返回所有节点的状态
+func (c *Client) Endpoints() []EndpointStatus {
+	return c.pool.status()
+}
+
+// Do 发送 JSON-RPC 请求并返回完整响应。
+// 传输层错误时立即切换到下一个候选节点（健康节点优先），所有节点都失败后按退避等待，
+// 再重试一轮，最多重试 RetryCount 轮；RPC 错误直接返回。
+func (c *Client) Do(ctx context.Context, method string, params ...interface{}) (*Response, error) {
+	req := c.newRequest(method, params)
+	candidates := c.pool.candidates()
+	if len(candidates) == 0 {
+		return nil, errors.New("未配置 RPC 节点")
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.retryCount; attempt++ {
+		if attempt > 0 {
+			if err := sleepBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+			// 按最新的健康状态重新排序
+			candidates = c.pool.candidates()
+		}
+
+		for _, url := range candidates {
+			start := time.Now()
+			resp, err := c.send(ctx, url, req)
+			if err == nil {
+				c.pool.markSuccess(url, time.Since(start))
+				return resp, nil
+			}
+			lastErr = err
+
+			var transportErr *TransportError
+			if !errors.As(err, &transportErr) || ctx.Err() != nil {
+				return nil, err
+			}
+			c.pool.markFailure(url, err)
+		}
+	}
+	return nil, lastErr
+}
+
+// Call 发送 JSON-RPC 请求并将 result 解析到 result 中，RPC 错误以 *RPCError 返回
+func (c *Client) Call(ctx context.Context, method string, result interface{}, params ...interface{}) error {
+	resp, err := c.Do(ctx, method, params...)
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("解析 %s 结果失败: %v", method, err)
+	}
+	return nil
+}
+
+// newRequest 创建带有递增 ID 的请求
+func (c *Client) newRequest(method string, params []interface{}) Request {
+	if params == nil {
+		params = []interface{}{}
+	}
+	return Request{
+		JSONRPC: "2.0",
+		ID:      c.nextID.Add(1),
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// send 向指定节点发送一次 HTTP 请求
+func (c *Client) send(ctx context.Context, url string, req Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &TransportError{URL: url, Err: err}
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, &TransportError{URL: url, Err: err}
+	}
+
+	// 网关类错误通常没有 JSON-RPC 响应体，视为传输层错误
+	switch httpResp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return nil, &TransportError{URL: url, StatusCode: httpResp.StatusCode}
+	}
+
+	var resp Response
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		if httpResp.StatusCode != http.StatusOK {
+			return nil, &TransportError{URL: url, StatusCode: httpResp.StatusCode}
+		}
+		return nil, fmt.Errorf("解析响应失败: %v", err)
+	}
+	return &resp, nil
+}
+
+// sleepBackoff 按指数退避加随机抖动等待，位移次数有上限，重试轮数很大时不会溢出为负数
+func sleepBackoff(ctx context.Context, attempt int) error {
+	if attempt > 16 {
+		attempt = 16
+	}
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := retryBaseDelay << (attempt - 1)
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,17 @@
+package benfen
+
+import (
+	"context"
+	"testing"
+)
+
+// 重试轮数很大时退避时间不能溢出为负数
+func TestSleepBackoffLargeAttempt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, attempt := range []int{1, 16, 35, 64, 1000} {
+		if err := sleepBackoff(ctx, attempt); err != context.Canceled {
+			t.Fatalf("第 %d 轮退避返回 %v，应为 context.Canceled", attempt, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package benfen
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// base58 字母表（比特币）
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// TransactionDigest 由 base64 编码的交易数据计算交易摘要：base58(blake2b256("TransactionData::" || tx))
+func TransactionDigest(txBytes string) (string, error) {
+	tx, err := base64.StdEncoding.DecodeString(txBytes)
+	if err != nil {
+		return "", fmt.Errorf("无效的交易字节: %v", err)
+	}
+	sum := blake2b.Sum256(append([]byte("TransactionData::"), tx...))
+	return encodeBase58(sum[:]), nil
+}
+
+// encodeBase58 base58 编码
+func encodeBase58(data []byte) string {
+	n := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, radix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// 前导零字节编码为 '1'
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
@@ -0,0 +1,28 @@
+package benfen
+
+// Benfen 节点 JSON-RPC 方法名
+const (
+	MethodUnsafePublish    = "unsafe_publish"
+	MethodUnsafeMoveCall   = "unsafe_moveCall"
+	MethodUnsafeBatch      = "unsafe_batchTransaction"
+	MethodUnsafeTransfer   = "unsafe_transferObject"
+	MethodLatestCheckpoint = "bfc_getLatestCheckpointSequenceNumber"
+	MethodGetChainID       = "bfc_getChainIdentifier"
+	MethodDryRun           = "bfc_dryRunTransactionBlock"
+	MethodGetCoins         = "bfcx_getCoins"
+	MethodGetCoinMetadata  = "bfcx_getCoinMetadata"
+	MethodGetTotalSupply   = "bfcx_getTotalSupply"
+	MethodGetBalance       = "bfcx_getBalance"
+	MethodExecute          = "bfc_executeTransactionBlock"
+	MethodGetTransaction   = "bfc_getTransactionBlock"
+	MethodGetObject        = "bfc_getObject"
+)
+
+// BFCCoinType 原生 gas 币类型
+const BFCCoinType = "0x2::bfc::BFC"
+
+// 执行交易时等待的确认级别
+const (
+	WaitForEffectsCert    = "WaitForEffectsCert"
+	WaitForLocalExecution = "WaitForLocalExecution"
+)
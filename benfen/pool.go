@@ -0,0 +1,223 @@
+package benfen
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 延迟的指数加权平滑系数
+const latencySmoothing = 0.3
+
+// endpoint 单个 RPC 节点的状态
+type endpoint struct {
+	url        string
+	healthy    bool
+	latency    time.Duration
+	checkpoint uint64
+	lastCheck  time.Time
+	lastError  string
+	failures   int
+}
+
+// EndpointStatus 节点状态快照，用于管理接口展示
+type EndpointStatus struct {
+	URL        string    `json:"url"`
+	Healthy    bool      `json:"healthy"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Checkpoint uint64    `json:"checkpoint"`
+	Lag        uint64    `json:"lag"`
+	LastCheck  time.Time `json:"last_check"`
+	LastError  string    `json:"last_error,omitempty"`
+	Failures   int       `json:"failures"`
+}
+
+// pool 管理多个 RPC 节点，按健康状态和延迟选择节点
+type pool struct {
+	mu        sync.RWMutex
+	endpoints []*endpoint
+	maxLag    uint64
+}
+
+// newPool 创建节点池，初始状态下所有节点视为健康
+func newPool(urls []string, maxLag uint64) *pool {
+	p := &pool{maxLag: maxLag}
+	for _, url := range urls {
+		p.endpoints = append(p.endpoints, &endpoint{url: url, healthy: true})
+	}
+	return p
+}
+
+// candidates 返回按优先级排序的节点地址：健康节点按延迟升序，不健康节点兜底
+func (p *pool) candidates() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	ordered := make([]*endpoint, len(p.endpoints))
+	copy(ordered, p.endpoints)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].healthy != ordered[j].healthy {
+			return ordered[i].healthy
+		}
+		return ordered[i].latency < ordered[j].latency
+	})
+
+	urls := make([]string, len(ordered))
+	for i, ep := range ordered {
+		urls[i] = ep.url
+	}
+	return urls
+}
+
+// markSuccess 记录一次成功请求的延迟
+func (p *pool) markSuccess(url string, latency time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ep := p.find(url); ep != nil {
+		ep.latency = smoothLatency(ep.latency, latency)
+		ep.failures = 0
+	}
+}
+
+// markFailure 记录传输失败，节点在下次健康检查通过前视为不健康
+func (p *pool) markFailure(url string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ep := p.find(url); ep != nil {
+		ep.healthy = false
+		ep.failures++
+		ep.lastError = err.Error()
+	}
+}
+
+// find 按地址查找节点，调用方需持有锁
+func (p *pool) find(url string) *endpoint {
+	for _, ep := range p.endpoints {
+		if ep.url == url {
+			return ep
+		}
+	}
+	return nil
+}
+
+// probe 并发探测所有节点的最新检查点，并将落后过多的节点标记为不健康
+func (p *pool) probe(ctx context.Context, c *Client) {
+	type result struct {
+		checkpoint uint64
+		latency    time.Duration
+		err        error
+	}
+
+	p.mu.RLock()
+	urls := make([]string, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		urls[i] = ep.url
+	}
+	p.mu.RUnlock()
+
+	results := make([]result, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			start := time.Now()
+			checkpoint, err := c.latestCheckpoint(ctx, url)
+			results[i] = result{checkpoint: checkpoint, latency: time.Since(start), err: err}
+		}(i, url)
+	}
+	wg.Wait()
+
+	var highest uint64
+	for _, res := range results {
+		if res.err == nil && res.checkpoint > highest {
+			highest = res.checkpoint
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	for i, url := range urls {
+		ep := p.find(url)
+		if ep == nil {
+			continue
+		}
+		res := results[i]
+		ep.lastCheck = now
+		if res.err != nil {
+			ep.healthy = false
+			ep.failures++
+			ep.lastError = res.err.Error()
+			continue
+		}
+		ep.checkpoint = res.checkpoint
+		ep.latency = smoothLatency(ep.latency, res.latency)
+		if highest-res.checkpoint > p.maxLag {
+			ep.healthy = false
+			ep.lastError = fmt.Sprintf("检查点落后 %d", highest-res.checkpoint)
+			continue
+		}
+		ep.healthy = true
+		ep.failures = 0
+		ep.lastError = ""
+	}
+}
+
+// status 返回所有节点的状态快照
+func (p *pool) status() []EndpointStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var highest uint64
+	for _, ep := range p.endpoints {
+		if ep.checkpoint > highest {
+			highest = ep.checkpoint
+		}
+	}
+
+	statuses := make([]EndpointStatus, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		statuses[i] = EndpointStatus{
+			URL:        ep.url,
+			Healthy:    ep.healthy,
+			LatencyMs:  ep.latency.Milliseconds(),
+			Checkpoint: ep.checkpoint,
+			Lag:        highest - ep.checkpoint,
+			LastCheck:  ep.lastCheck,
+			LastError:  ep.lastError,
+			Failures:   ep.failures,
+		}
+	}
+	return statuses
+}
+
+// latestCheckpoint 直接向指定节点查询最新检查点序号，不做重试
+func (c *Client) latestCheckpoint(ctx context.Context, url string) (uint64, error) {
+	resp, err := c.send(ctx, url, c.newRequest(MethodLatestCheckpoint, nil))
+	if err != nil {
+		return 0, err
+	}
+	if resp.Error != nil {
+		return 0, resp.Error
+	}
+
+	// 检查点序号以字符串形式返回
+	var text string
+	if err := json.Unmarshal(resp.Result, &text); err != nil {
+		return 0, fmt.Errorf("解析检查点失败: %v", err)
+	}
+	return strconv.ParseUint(text, 10, 64)
+}
+
+// smoothLatency 对延迟做指数加权平滑
+func smoothLatency(previous, sample time.Duration) time.Duration {
+	if previous == 0 {
+		return sample
+	}
+	return time.Duration(float64(previous)*(1-latencySmoothing) + float64(sample)*latencySmoothing)
+}
@@ -0,0 +1,365 @@
+package benfen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 脱敏后的占位值
+const redactedValue = "[REDACTED]"
+
+// 签名所在的参数位置：bfc_executeTransactionBlock(txBytes, signatures, ...)
+const executeSignaturesParam = 1
+
+// hexValue 0x 开头的十六进制值，脱敏时保持形状以便地址和对象 ID 仍可解析
+var hexValue = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+
+// base64String JSON 中较长的 base64 字符串，如交易字节和模块字节码
+var base64String = regexp.MustCompile(`"[A-Za-z0-9+/]{40,}={0,2}"`)
+
+// 按原始字节替换的十六进制值的最小字节数，过短的值容易误伤无关字节
+const minBinarySecretLen = 20
+
+// Fixture 一次录制的请求和响应，请求不含 JSON-RPC ID
+type Fixture struct {
+	Seq      int             `json:"seq"`
+	Method   string          `json:"method"`
+	Params   json.RawMessage `json:"params"`
+	Status   int             `json:"status"`
+	Response json.RawMessage `json:"response"`
+}
+
+// key 按方法和参数匹配回放
+func (f *Fixture) key() string {
+	return f.Method + " " + string(f.Params)
+}
+
+// Redactor 对录制的流量脱敏：执行交易的签名替换为占位值，
+// 配置的敏感字符串（如内部账户地址）在请求和响应中统一替换为确定性的假值。
+// 地址等十六进制值在 base64 编码的交易字节中以原始字节出现，同样按字节替换后重新编码。
+// 回放时用同样的规则处理请求，使脱敏后的录制文件仍能匹配
+type Redactor struct {
+	replacements []string
+	// binary 原始字节形式的敏感值及其同样长度的假值
+	binary [][2][]byte
+}
+
+// NewRedactor 创建脱敏规则，secrets 为需要替换的字符串
+func NewRedactor(secrets []string) *Redactor {
+	r := &Redactor{}
+	// 先替换长的值，避免短值是长值的子串时替换不完整
+	sorted := append([]string(nil), secrets...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	for _, secret := range sorted {
+		if secret == "" {
+			continue
+		}
+		r.replacements = append(r.replacements, secret, placeholder(secret))
+		// 十六进制值大小写不敏感，节点可能返回不同的大小写
+		if hexValue.MatchString(secret) && strings.ToLower(secret) != secret {
+			r.replacements = append(r.replacements, strings.ToLower(secret), placeholder(secret))
+		}
+		if !hexValue.MatchString(secret) {
+			continue
+		}
+		if raw, err := hex.DecodeString(secret[2:]); err == nil && len(raw) >= minBinarySecretLen {
+			fake, _ := hex.DecodeString(placeholder(secret)[2:])
+			r.binary = append(r.binary, [2][]byte{raw, fake})
+		}
+	}
+	return r
+}
+
+// placeholder 生成确定性的假值，十六进制值替换为同样长度的十六进制
+func placeholder(secret string) string {
+	if !hexValue.MatchString(secret) {
+		return redactedValue
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(secret)))
+	digits := strings.Repeat(hex.EncodeToString(sum[:]), (len(secret)-2)/64+1)
+	return "0x" + digits[:len(secret)-2]
+}
+
+// text 替换文本中的敏感字符串，以及 base64 字符串中原始字节形式的敏感值
+func (r *Redactor) text(data []byte) []byte {
+	if r == nil || len(r.replacements) == 0 {
+		return data
+	}
+	replacer := strings.NewReplacer(r.replacements...)
+	data = []byte(replacer.Replace(string(data)))
+	return base64String.ReplaceAllFunc(data, func(quoted []byte) []byte {
+		decoded, err := base64.StdEncoding.DecodeString(string(quoted[1 : len(quoted)-1]))
+		if err != nil {
+			return quoted
+		}
+		// 编码后的内容可能包含文本形式（如 JSON）或原始字节形式的敏感值
+		raw := []byte(replacer.Replace(string(decoded)))
+		for _, pair := range r.binary {
+			raw = bytes.ReplaceAll(raw, pair[0], pair[1])
+		}
+		if bytes.Equal(raw, decoded) {
+			return quoted
+		}
+		return []byte(`"` + base64.StdEncoding.EncodeToString(raw) + `"`)
+	})
+}
+
+// request 脱敏请求参数
+func (r *Redactor) request(method string, params json.RawMessage) json.RawMessage {
+	if method == MethodExecute {
+		var values []json.RawMessage
+		if err := json.Unmarshal(params, &values); err == nil && len(values) > executeSignaturesParam {
+			values[executeSignaturesParam] = json.RawMessage(`["` + redactedValue + `"]`)
+			params, _ = json.Marshal(values)
+		}
+	}
+	return compact(r.text(params))
+}
+
+// compact 去除 JSON 中的空白，使匹配不受格式影响
+func compact(data []byte) json.RawMessage {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, data); err != nil {
+		return data
+	}
+	return buf.Bytes()
+}
+
+// parseRequest 解析 HTTP 请求中的 JSON-RPC 方法和参数
+func parseRequest(body []byte) (Request, json.RawMessage, error) {
+	var req struct {
+		Request
+		Params json.RawMessage `json:"params"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return Request{}, nil, fmt.Errorf("解析 JSON-RPC 请求失败: %v", err)
+	}
+	if len(req.Params) == 0 {
+		req.Params = json.RawMessage("[]")
+	}
+	return req.Request, req.Params, nil
+}
+
+// Recorder 录制 RPC 流量的 http.RoundTripper，每次请求保存为目录下的一个脱敏后的 JSON 文件
+type Recorder struct {
+	dir      string
+	redactor *Redactor
+	next     http.RoundTripper
+	seq      atomic.Int64
+}
+
+// NewRecorder 创建录制器，next 为空时使用 http.DefaultTransport。
+// 目录中已有的录制文件会保留，新文件的序号接在后面
+func NewRecorder(dir string, redactor *Redactor, next http.RoundTripper) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建录制目录失败: %v", err)
+	}
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{dir: dir, redactor: redactor, next: next}
+	r.seq.Store(int64(len(existing)))
+	return r, nil
+}
+
+// RoundTrip 转发请求并保存请求和响应，保存失败不影响请求本身
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if err := r.save(body, resp.StatusCode, respBody); err != nil {
+		log.Printf("保存 RPC 录制失败: %v", err)
+	}
+	return resp, nil
+}
+
+// save 脱敏并写出录制文件
+func (r *Recorder) save(body []byte, status int, respBody []byte) error {
+	rpcReq, params, err := parseRequest(body)
+	if err != nil {
+		return err
+	}
+	fixture := Fixture{
+		Seq:    int(r.seq.Add(1)),
+		Method: rpcReq.Method,
+		Params: r.redactor.request(rpcReq.Method, params),
+		Status: status,
+	}
+	if json.Valid(respBody) {
+		var resp map[string]json.RawMessage
+		if err := json.Unmarshal(respBody, &resp); err == nil {
+			delete(resp, "id")
+			respBody, _ = json.Marshal(resp)
+		}
+		fixture.Response = compact(r.redactor.text(respBody))
+	} else {
+		encoded, _ := json.Marshal(string(r.redactor.text(respBody)))
+		fixture.Response = encoded
+	}
+
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%04d_%s.json", fixture.Seq, fixture.Method)
+	return os.WriteFile(filepath.Join(r.dir, name), data, 0644)
+}
+
+// ReplayTransport 回放录制文件的 http.RoundTripper，不访问网络。
+// 按方法和脱敏后的参数匹配，相同请求按录制顺序依次返回，用完后重复返回最后一次的响应；
+// 没有匹配的录制时返回 JSON-RPC 错误
+type ReplayTransport struct {
+	redactor *Redactor
+
+	mu       sync.Mutex
+	fixtures map[string][]*Fixture
+	served   map[string]int
+}
+
+// NewReplayTransport 从目录加载录制文件
+func NewReplayTransport(dir string, redactor *Redactor) (*ReplayTransport, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("回放目录 %s 中没有录制文件", dir)
+	}
+
+	var fixtures []*Fixture
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var fixture Fixture
+		if err := json.Unmarshal(data, &fixture); err != nil {
+			return nil, fmt.Errorf("解析录制文件 %s 失败: %v", file, err)
+		}
+		fixture.Params = compact(fixture.Params)
+		fixtures = append(fixtures, &fixture)
+	}
+	sort.SliceStable(fixtures, func(i, j int) bool { return fixtures[i].Seq < fixtures[j].Seq })
+
+	t := &ReplayTransport{
+		redactor: redactor,
+		fixtures: make(map[string][]*Fixture),
+		served:   make(map[string]int),
+	}
+	for _, fixture := range fixtures {
+		t.fixtures[fixture.key()] = append(t.fixtures[fixture.key()], fixture)
+	}
+	return t, nil
+}
+
+// RoundTrip 返回匹配的录制响应，JSON-RPC ID 替换为请求的 ID
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	rpcReq, params, err := parseRequest(body)
+	if err != nil {
+		return nil, err
+	}
+
+	key := (&Fixture{Method: rpcReq.Method, Params: t.redactor.request(rpcReq.Method, params)}).key()
+	fixture := t.next(key)
+	if fixture == nil {
+		return replayResponse(req, http.StatusOK, map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      rpcReq.ID,
+			"error":   RPCError{Code: -32000, Message: "没有匹配的录制: " + key},
+		})
+	}
+
+	var resp map[string]json.RawMessage
+	if err := json.Unmarshal(fixture.Response, &resp); err != nil {
+		// 非 JSON 响应（如网关错误页）原样返回
+		var text string
+		_ = json.Unmarshal(fixture.Response, &text)
+		return rawResponse(req, fixture.Status, []byte(text)), nil
+	}
+	resp["id"], _ = json.Marshal(rpcReq.ID)
+	return replayResponse(req, fixture.Status, resp)
+}
+
+// next 取出下一条匹配的录制
+func (t *ReplayTransport) next(key string) *Fixture {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fixtures := t.fixtures[key]
+	if len(fixtures) == 0 {
+		return nil
+	}
+	i := t.served[key]
+	if i >= len(fixtures) {
+		i = len(fixtures) - 1
+	}
+	t.served[key] = i + 1
+	return fixtures[i]
+}
+
+func replayResponse(req *http.Request, status int, value interface{}) (*http.Response, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return rawResponse(req, status, data), nil
+}
+
+func rawResponse(req *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,62 @@
+package benfen
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testSecret = "0x6f0f9a9a72f7d48b8fcbfa09ebb61123d847aaad5760297d68c64795bad514b1"
+
+// 交易字节中的地址以原始字节出现，脱敏后长度不变且不再包含原地址
+func TestRedactorTxBytes(t *testing.T) {
+	address, _ := hex.DecodeString(strings.TrimPrefix(testSecret, "0x"))
+	tx := append(append([]byte{0x00, 0x00}, address...), bytes.Repeat([]byte{0x07}, 40)...)
+	params, _ := json.Marshal([]interface{}{base64.StdEncoding.EncodeToString(tx), []string{"sig"}})
+
+	redactor := NewRedactor([]string{testSecret})
+	redacted := redactor.request(MethodExecute, params)
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(redacted, &values); err != nil {
+		t.Fatalf("脱敏后的参数不是 JSON: %v", err)
+	}
+	var txBytes string
+	if err := json.Unmarshal(values[0], &txBytes); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(txBytes)
+	if err != nil {
+		t.Fatalf("脱敏后的交易字节不是 base64: %v", err)
+	}
+	if len(decoded) != len(tx) {
+		t.Fatalf("脱敏后交易字节长度为 %d，应为 %d", len(decoded), len(tx))
+	}
+	if bytes.Contains(decoded, address) {
+		t.Fatalf("交易字节中仍包含原地址")
+	}
+	fake, _ := hex.DecodeString(strings.TrimPrefix(placeholder(testSecret), "0x"))
+	if !bytes.Contains(decoded, fake) {
+		t.Fatalf("交易字节中的地址没有替换为确定性的假值")
+	}
+	if strings.Contains(string(redacted), "sig") {
+		t.Fatalf("签名没有脱敏")
+	}
+
+	// 回放时对已脱敏的请求再次脱敏，结果不变，保证仍能匹配录制
+	if again := redactor.request(MethodExecute, redacted); !bytes.Equal(again, redacted) {
+		t.Fatalf("重复脱敏结果不一致")
+	}
+}
+
+// 短于 0x 前缀的脱敏值不能导致 panic，按普通字符串替换
+func TestRedactorShortSecret(t *testing.T) {
+	redactor := NewRedactor([]string{"k", "0x"})
+	redacted := redactor.text([]byte(`{"key":"k"}`))
+	if strings.Contains(string(redacted), `"k"`) {
+		t.Fatalf("短脱敏值没有替换: %s", redacted)
+	}
+}
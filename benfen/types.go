@@ -0,0 +1,223 @@
+// Package benfen 提供访问 Benfen 节点的 JSON-RPC 客户端
+package benfen
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Request JSON-RPC 请求
+type Request struct {
+	JSONRPC string        `json:"jsonrpc"`
+	ID      uint64        `json:"id"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+}
+
+// Response JSON-RPC 响应
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError JSON-RPC 错误对象
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("rpc 错误 %d: %s", e.Code, e.Message)
+}
+
+// TransportError 请求未能得到有效 JSON-RPC 响应时返回的错误
+type TransportError struct {
+	URL        string
+	StatusCode int
+	Err        error
+}
+
+func (e *TransportError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("请求 %s 失败: HTTP %d", e.URL, e.StatusCode)
+	}
+	return fmt.Sprintf("请求 %s 失败: %v", e.URL, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// Uint64 兼容字符串和数字两种编码的 64 位无符号整数，序列化为字符串
+type Uint64 uint64
+
+// UnmarshalJSON 解析字符串或数字形式的整数
+func (u *Uint64) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	n, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的整数: %s", string(data))
+	}
+	*u = Uint64(n)
+	return nil
+}
+
+// MarshalJSON 序列化为字符串，避免 JavaScript 精度丢失
+func (u Uint64) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(u), 10))
+}
+
+// ObjectRef 对象引用
+type ObjectRef struct {
+	ObjectID string `json:"objectId"`
+	Version  Uint64 `json:"version"`
+	Digest   string `json:"digest"`
+}
+
+// TransactionBlockBytes unsafe_* 构建方法返回的未签名交易
+type TransactionBlockBytes struct {
+	TxBytes      string            `json:"txBytes"`
+	Gas          []ObjectRef       `json:"gas"`
+	InputObjects []json.RawMessage `json:"inputObjects"`
+}
+
+// ExecutionStatus 交易执行状态
+type ExecutionStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// GasCostSummary 交易 gas 消耗
+type GasCostSummary struct {
+	ComputationCost         Uint64 `json:"computationCost"`
+	StorageCost             Uint64 `json:"storageCost"`
+	StorageRebate           Uint64 `json:"storageRebate"`
+	NonRefundableStorageFee Uint64 `json:"nonRefundableStorageFee"`
+}
+
+// TransactionEffects 交易效果
+type TransactionEffects struct {
+	Status            ExecutionStatus `json:"status"`
+	GasUsed           GasCostSummary  `json:"gasUsed"`
+	TransactionDigest string          `json:"transactionDigest"`
+}
+
+// DryRunResult 交易预执行结果
+type DryRunResult struct {
+	Effects TransactionEffects `json:"effects"`
+}
+
+// Coin 地址持有的币对象
+type Coin struct {
+	CoinType     string `json:"coinType"`
+	CoinObjectID string `json:"coinObjectId"`
+	Version      Uint64 `json:"version"`
+	Digest       string `json:"digest"`
+	Balance      Uint64 `json:"balance"`
+}
+
+// CoinPage 分页查询的币列表
+type CoinPage struct {
+	Data        []Coin  `json:"data"`
+	NextCursor  *string `json:"nextCursor"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
+// CoinMetadata 代币元数据
+type CoinMetadata struct {
+	ID          string `json:"id"`
+	Decimals    uint8  `json:"decimals"`
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	Description string `json:"description"`
+	IconURL     string `json:"iconUrl,omitempty"`
+}
+
+// ObjectData 链上对象
+type ObjectData struct {
+	ObjectID string          `json:"objectId"`
+	Version  Uint64          `json:"version"`
+	Digest   string          `json:"digest"`
+	Type     string          `json:"type,omitempty"`
+	Owner    json.RawMessage `json:"owner,omitempty"`
+	Content  json.RawMessage `json:"content,omitempty"`
+}
+
+// ObjectResponse 查询对象的结果，对象不存在时 Data 为空
+type ObjectResponse struct {
+	Data  *ObjectData     `json:"data,omitempty"`
+	Error json.RawMessage `json:"error,omitempty"`
+}
+
+// Supply 代币总供应量
+type Supply struct {
+	Value Uint64 `json:"value"`
+}
+
+// Balance 地址持有的某类币的合计余额
+type Balance struct {
+	CoinType        string `json:"coinType"`
+	CoinObjectCount int    `json:"coinObjectCount"`
+	TotalBalance    Uint64 `json:"totalBalance"`
+}
+
+// MoveCallParams 批量交易中的一次 Move 调用
+type MoveCallParams struct {
+	PackageObjectID string        `json:"packageObjectId"`
+	Module          string        `json:"module"`
+	Function        string        `json:"function"`
+	TypeArguments   []string      `json:"typeArguments"`
+	Arguments       []interface{} `json:"arguments"`
+}
+
+// BatchTransactionParams unsafe_batchTransaction 的单个交易参数
+type BatchTransactionParams struct {
+	MoveCall *MoveCallParams `json:"moveCallRequestParams,omitempty"`
+}
+
+// TransactionBlockResponseOptions 查询或执行交易时需要返回的内容
+type TransactionBlockResponseOptions struct {
+	ShowInput          bool `json:"showInput,omitempty"`
+	ShowEffects        bool `json:"showEffects,omitempty"`
+	ShowEvents         bool `json:"showEvents,omitempty"`
+	ShowObjectChanges  bool `json:"showObjectChanges,omitempty"`
+	ShowBalanceChanges bool `json:"showBalanceChanges,omitempty"`
+}
+
+// ObjectChange 交易引起的对象变更
+type ObjectChange struct {
+	Type       string          `json:"type"`
+	Sender     string          `json:"sender,omitempty"`
+	Owner      json.RawMessage `json:"owner,omitempty"`
+	ObjectType string          `json:"objectType,omitempty"`
+	ObjectID   string          `json:"objectId,omitempty"`
+	PackageID  string          `json:"packageId,omitempty"`
+	Modules    []string        `json:"modules,omitempty"`
+	Version    Uint64          `json:"version"`
+	Digest     string          `json:"digest"`
+}
+
+// TransactionBlock 交易内容（showInput），只解析用到的字段
+type TransactionBlock struct {
+	Data TransactionBlockData `json:"data"`
+}
+
+// TransactionBlockData 交易数据
+type TransactionBlockData struct {
+	Sender string `json:"sender"`
+}
+
+// TransactionBlockResponse 执行或查询交易的结果
+type TransactionBlockResponse struct {
+	Digest                  string              `json:"digest"`
+	Transaction             *TransactionBlock   `json:"transaction,omitempty"`
+	Effects                 *TransactionEffects `json:"effects,omitempty"`
+	ObjectChanges           []ObjectChange      `json:"objectChanges,omitempty"`
+	ConfirmedLocalExecution *bool               `json:"confirmedLocalExecution,omitempty"`
+	Errors                  []string            `json:"errors,omitempty"`
+}
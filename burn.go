@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/benfen"
+)
+
+// 单笔销毁交易最多合并的币数量
+const burnMaxCoins = 100
+
+// BurnRequest 销毁请求，从发送者持有的该类型的币中扣除
+type BurnRequest struct {
+	TokenOpRequest
+	// TreasuryCapID 未指定时使用发布记录中的 TreasuryCap
+	TreasuryCapID string `json:"treasury_cap_id,omitempty"`
+	// Amount 按代币精度表示的可读数量
+	Amount string `json:"amount"`
+}
+
+// BurnCoinSelection 销毁使用的币，Merge 中的币先合并到 Primary，多出的 Change 拆分后退回发送者
+type BurnCoinSelection struct {
+	Primary string   `json:"primary"`
+	Merge   []string `json:"merge,omitempty"`
+	Total   uint64   `json:"total,string"`
+	Change  uint64   `json:"change,string"`
+}
+
+// selectBurnCoins 优先选择余额足够的最小单个币，否则按余额降序合并最少数量的币
+func selectBurnCoins(coins []benfen.Coin, amount uint64) (*BurnCoinSelection, error) {
+	sort.Slice(coins, func(i, j int) bool {
+		return coins[i].Balance < coins[j].Balance
+	})
+
+	var total uint64
+	for _, coin := range coins {
+		if uint64(coin.Balance) >= amount {
+			return &BurnCoinSelection{
+				Primary: coin.CoinObjectID,
+				Total:   uint64(coin.Balance),
+				Change:  uint64(coin.Balance) - amount,
+			}, nil
+		}
+		total += uint64(coin.Balance)
+	}
+	if total < amount {
+		return nil, fmt.Errorf("余额不足: 可用 %d, 需要 %d", total, amount)
+	}
+
+	selection := &BurnCoinSelection{}
+	for i := len(coins) - 1; i >= 0 && selection.Total < amount; i-- {
+		if selection.Primary == "" {
+			selection.Primary = coins[i].CoinObjectID
+		} else {
+			selection.Merge = append(selection.Merge, coins[i].CoinObjectID)
+		}
+		selection.Total += uint64(coins[i].Balance)
+	}
+	if len(selection.Merge)+1 > burnMaxCoins {
+		return nil, fmt.Errorf("需要合并 %d 个币，超过单笔交易上限 %d，请先合并币", len(selection.Merge)+1, burnMaxCoins)
+	}
+	selection.Change = selection.Total - amount
+	return selection, nil
+}
+
+// burnCalls 生成合并、拆分找零和 coin::burn 的调用序列
+func burnCalls(coinType, treasuryCapID, sender string, selection *BurnCoinSelection) []MoveCall {
+	typeArgs := []string{coinType}
+	var calls []MoveCall
+	for _, coin := range selection.Merge {
+		calls = append(calls, MoveCall{
+			Package:  "0x2",
+			Module:   "coin",
+			Function: "join",
+			TypeArgs: typeArgs,
+			Args:     []interface{}{selection.Primary, coin},
+		})
+	}
+	if selection.Change > 0 {
+		calls = append(calls, MoveCall{
+			Package:  "0x2",
+			Module:   "pay",
+			Function: "split_and_transfer",
+			TypeArgs: typeArgs,
+			Args:     []interface{}{selection.Primary, strconv.FormatUint(selection.Change, 10), sender},
+		})
+	}
+	return append(calls, MoveCall{
+		Package:  "0x2",
+		Module:   "coin",
+		Function: "burn",
+		TypeArgs: typeArgs,
+		Args:     []interface{}{treasuryCapID, selection.Primary},
+	})
+}
+
+// burnToken 构建销毁交易，返回未签名交易和销毁后的预期总供应量
+func burnToken(w http.ResponseWriter, r *http.Request) {
+	coinType, err := parseCoinType(chi.URLParam(r, "coinType"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	if coinType == normalizeCoinType(benfen.BFCCoinType) {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "不能销毁 BFC",
+		})
+		return
+	}
+
+	var req BurnRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+
+	r, ok := prepareTokenOp(w, r, &req.TokenOpRequest)
+	if !ok {
+		return
+	}
+
+	tokenID, ok := resolveTreasuryCap(r.Context(), w, coinType, &req.TreasuryCapID)
+	if !ok {
+		return
+	}
+
+	metadata, err := fetchCoinMetadata(r.Context(), coinType)
+	if err != nil {
+		writeCoinMetadataError(w, err)
+		return
+	}
+	amount, err := parseAmount(req.Amount, metadata.Decimals)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("amount 无效: %v", err),
+		})
+		return
+	}
+
+	supply, err := fetchTotalSupply(r.Context(), coinType)
+	if err != nil {
+		writeRPCError(w, "查询总供应量失败", err)
+		return
+	}
+	if amount > supply {
+		writeResponse(w, http.StatusUnprocessableEntity, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("销毁数量超过总供应量 %s", formatAmount(supply, metadata.Decimals)),
+		})
+		return
+	}
+
+	coins, err := fetchAllCoins(r.Context(), req.Sender, coinType)
+	if err != nil {
+		writeRPCError(w, "查询持有的币失败", err)
+		return
+	}
+	selection, err := selectBurnCoins(coins, amount)
+	if err != nil {
+		writeResponse(w, http.StatusUnprocessableEntity, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	calls := burnCalls(coinType, req.TreasuryCapID, req.Sender, selection)
+	build := moveCallBuilder(req.Sender, calls[0])
+	if len(calls) > 1 {
+		build = batchBuilder(req.Sender, calls)
+	}
+	expected := supply - amount
+	runTokenOp(w, r, req.TokenOpRequest, TxKindBurn, tokenID, build, map[string]interface{}{
+		"coin_type":            coinType,
+		"decimals":             metadata.Decimals,
+		"treasury_cap_id":      req.TreasuryCapID,
+		"amount":               formatAmount(amount, metadata.Decimals),
+		"base_amount":          strconv.FormatUint(amount, 10),
+		"coins":                selection,
+		"current_supply":       formatAmount(supply, metadata.Decimals),
+		"expected_supply":      formatAmount(expected, metadata.Decimals),
+		"expected_base_supply": strconv.FormatUint(expected, 10),
+		"calls":                calls,
+	})
+}
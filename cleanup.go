@@ -23,7 +23,7 @@ func cleanupOldDirectories() {
 	}
 
 	// 正则表达式匹配 coin_tmp_时间戳 格式的目录
-	tmpDirPattern := regexp.MustCompile(`^coin_tmp_(\\d+)$`)
+	tmpDirPattern := regexp.MustCompile(`^coin_tmp_(\d+)$`)
 	currentTime := time.Now().Unix()
 	// 从配置文件获取保留时间
 	retentionMinutes := GetCleanupRetentionMinutes()
@@ -57,6 +57,10 @@ func cleanupOldDirectories() {
 				age := time.Duration(currentTime-timestamp) * time.Second
 				log.Printf("清理任务: 成功删除过期目录 %s (存在时间: %v)", entry.Name(), age)
 				cleanedCount++
+				emitEvent(EventCleanupRemoved, map[string]interface{}{
+					"directory": entry.Name(),
+					"age":       age.String(),
+				})
 			}
 		}
 	}
@@ -83,6 +87,7 @@ func startCleanupScheduler() {
 	go func() {
 		for range ticker.C {
 			cleanupOldDirectories()
+			jobs.prune(time.Duration(retentionMinutes) * time.Minute)
 		}
 	}()
 }
\ No newline at end of file
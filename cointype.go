@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"obc_coin_api/address"
+)
+
+// Move 标识符
+var moveIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseCoinType 解析路径中的代币类型 <地址>::<模块>::<类型>，地址统一为完整的 0x 格式
+func parseCoinType(raw string) (string, error) {
+	coinType, err := url.PathUnescape(raw)
+	if err != nil {
+		return "", fmt.Errorf("代币类型编码无效: %v", err)
+	}
+
+	parts := strings.Split(coinType, "::")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("代币类型格式应为 <地址>::<模块>::<类型>: %s", coinType)
+	}
+	addr, err := address.Parse(parts[0])
+	if err != nil {
+		return "", fmt.Errorf("代币类型中的地址无效: %v", err)
+	}
+	for _, ident := range parts[1:] {
+		if !moveIdentifier.MatchString(ident) {
+			return "", fmt.Errorf("代币类型中的标识符无效: %s", ident)
+		}
+	}
+	return fmt.Sprintf("%s::%s::%s", addr.Hex(), parts[1], parts[2]), nil
+}
+
+// normalizeCoinType 尽量将代币类型中的地址统一为完整格式，无法解析时原样返回
+func normalizeCoinType(coinType string) string {
+	if normalized, err := parseCoinType(coinType); err == nil {
+		return normalized
+	}
+	return coinType
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"obc_coin_api/keystore"
+	"obc_coin_api/mockrpc"
+)
+
+// runCommand 执行命令行子命令
+func runCommand(args []string) error {
+	switch args[0] {
+	case "keystore":
+		return runKeystoreCommand(args[1:])
+	case "mock-rpc":
+		return runMockRPCCommand(args[1:])
+	case "audit":
+		return runAuditCommand(args[1:])
+	default:
+		return fmt.Errorf("未知命令: %s", args[0])
+	}
+}
+
+// runKeystoreCommand 生成或导入加密密钥文件
+//
+//	keystore new -scheme ed25519 -out keystore.json
+//	keystore import -scheme secp256k1 -key <hex私钥> -out keystore.json
+func runKeystoreCommand(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("用法: keystore new|import [参数]")
+	}
+
+	fs := flag.NewFlagSet("keystore "+args[0], flag.ContinueOnError)
+	scheme := fs.String("scheme", string(keystore.SchemeEd25519), "签名方案: ed25519 或 secp256k1")
+	out := fs.String("out", "keystore.json", "输出的密钥文件路径")
+	privateKey := fs.String("key", "", "导入的十六进制私钥（仅 import）")
+	passphraseEnv := fs.String("passphrase-env", "OBC_KEYSTORE_PASSPHRASE", "存放口令的环境变量")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	passphrase := os.Getenv(*passphraseEnv)
+	if passphrase == "" {
+		return fmt.Errorf("环境变量 %s 未设置", *passphraseEnv)
+	}
+
+	var key *keystore.Key
+	var err error
+	switch args[0] {
+	case "new":
+		key, err = keystore.Generate(keystore.Scheme(*scheme))
+	case "import":
+		var raw []byte
+		raw, err = hex.DecodeString(strings.TrimPrefix(*privateKey, "0x"))
+		if err != nil {
+			return fmt.Errorf("无效的私钥: %v", err)
+		}
+		key, err = keystore.NewKey(keystore.Scheme(*scheme), raw)
+	default:
+		return fmt.Errorf("未知的 keystore 子命令: %s", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := keystore.Save(*out, key, passphrase); err != nil {
+		return fmt.Errorf("写入密钥文件失败: %v", err)
+	}
+	fmt.Printf("密钥文件已生成: %s\n地址: %s\n方案: %s\n", *out, key.Address(), key.Scheme())
+	return nil
+}
+
+// fundFlags 可重复的 -fund 地址=数量 参数
+type fundFlags map[string]uint64
+
+func (f fundFlags) String() string {
+	return fmt.Sprint(map[string]uint64(f))
+}
+
+func (f fundFlags) Set(value string) error {
+	owner, amount, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("格式应为 地址=数量: %s", value)
+	}
+	n, err := strconv.ParseUint(amount, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的数量 %s: %v", amount, err)
+	}
+	f[owner] += n
+	return nil
+}
+
+// runMockRPCCommand 启动内存中的模拟 Benfen 节点，将网络的 urls 指向监听地址即可本地联调
+//
+//	mock-rpc -listen 127.0.0.1:9000 -fund 0x<地址>=100000000000 -script failures.json
+func runMockRPCCommand(args []string) error {
+	fs := flag.NewFlagSet("mock-rpc", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:9000", "监听地址")
+	chainID := fs.String("chain-id", mockrpc.DefaultChainID, "返回的链 ID，需与网络配置的 chain_id 一致")
+	decimals := fs.Uint("decimals", 9, "发布的代币精度")
+	script := fs.String("script", "", "故障脚本 JSON 文件")
+	accounts := fundFlags{}
+	fs.Var(accounts, "fund", "初始 BFC 余额，格式 地址=数量（最小单位），可重复")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *decimals > 18 {
+		return fmt.Errorf("decimals 不能超过 18")
+	}
+	publishDecimals := uint8(*decimals)
+
+	opts := mockrpc.Options{
+		ChainID:  *chainID,
+		Publish:  mockrpc.PublishTemplate{Decimals: &publishDecimals},
+		Accounts: accounts,
+	}
+	if *script != "" {
+		failures, err := mockrpc.LoadFailures(*script)
+		if err != nil {
+			return err
+		}
+		opts.Failures = failures
+	}
+	server, err := mockrpc.New(opts)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("模拟 Benfen 节点监听 http://%s，链 ID %s，初始账户 %d 个，故障 %d 条", *listen, opts.ChainID, len(accounts), len(opts.Failures))
+	return http.ListenAndServe(*listen, server)
+}
+
+// runAuditCommand 校验审计日志的哈希链，HMAC 密钥从 audit.key_env 指定的环境变量读取
+//
+//	audit verify -file data/audit.log -head <外部保存的最新哈希>
+func runAuditCommand(args []string) error {
+	if len(args) == 0 || args[0] != "verify" {
+		return fmt.Errorf("用法: audit verify [参数]")
+	}
+
+	fs := flag.NewFlagSet("audit verify", flag.ContinueOnError)
+	file := fs.String("file", "", "审计日志文件，默认使用 config.yaml 中的 audit.path")
+	head := fs.String("head", "", "期望的最新记录哈希，用于发现末尾记录被截断")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := LoadConfig("config.yaml"); err != nil {
+		log.Printf("加载配置文件失败: %v，使用默认配置", err)
+	}
+	if *file == "" {
+		*file = GetAuditPath()
+	}
+	key := []byte(os.Getenv(GetAuditKeyEnv()))
+	if len(key) == 0 {
+		log.Printf("环境变量 %s 未设置，只能校验未使用 HMAC 的记录", GetAuditKeyEnv())
+	}
+
+	count, last, err := verifyAuditLog(*file, key)
+	if err != nil {
+		return fmt.Errorf("审计日志校验失败（已校验 %d 条）: %v", count, err)
+	}
+	if *head != "" && *head != last {
+		return fmt.Errorf("审计日志最新哈希为 %s，与期望的 %s 不一致，末尾记录可能被截断", last, *head)
+	}
+	fmt.Printf("审计日志校验通过: %s\n记录数: %d\n最新哈希: %s\n", *file, count, last)
+	return nil
+}
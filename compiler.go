@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CompilerInfo 记录启动时检测到的编译器信息
+type CompilerInfo struct {
+	BinaryPath string `json:"binary_path"`
+	Version    string `json:"version"`
+	Raw        string `json:"raw,omitempty"`
+	Supported  bool   `json:"supported"`
+	Degraded   bool   `json:"degraded"`
+	Message    string `json:"message,omitempty"`
+}
+
+var (
+	compilerMu   sync.RWMutex
+	compilerInfo CompilerInfo
+)
+
+// 匹配版本号，例如 "bfc 1.1.5-abcdef" 中的 1.1.5
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)\.(\d+)`)
+
+// GetCompilerInfo 获取编译器信息快照
+func GetCompilerInfo() CompilerInfo {
+	compilerMu.RLock()
+	defer compilerMu.RUnlock()
+	return compilerInfo
+}
+
+// checkCompiler 检查编译器二进制并校验版本，不满足要求时按配置拒绝启动或降级运行
+func checkCompiler() {
+	info := CompilerInfo{BinaryPath: GetBFCBinaryPath()}
+
+	if err := inspectCompiler(&info); err != nil {
+		if GetBFCVersionMismatchMode() != "degraded" {
+			log.Fatalf("编译器检查失败: %v", err)
+		}
+		info.Degraded = true
+		info.Message = err.Error()
+		log.Printf("编译器检查失败，服务降级运行: %v", err)
+	} else {
+		log.Printf("编译器检查通过: %s (版本 %s)", info.BinaryPath, info.Version)
+	}
+
+	compilerMu.Lock()
+	compilerInfo = info
+	compilerMu.Unlock()
+}
+
+// inspectCompiler 执行版本命令并与配置的版本范围比较
+func inspectCompiler(info *CompilerInfo) error {
+	stat, err := os.Stat(info.BinaryPath)
+	if err != nil {
+		return fmt.Errorf("BFC 二进制不可用 %s: %v", info.BinaryPath, err)
+	}
+	if stat.IsDir() || stat.Mode()&0111 == 0 {
+		return fmt.Errorf("BFC 二进制不可执行: %s", info.BinaryPath)
+	}
+
+	output, err := exec.Command(info.BinaryPath, "--version").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("执行版本命令失败: %v, 输出: %s", err, string(output))
+	}
+	info.Raw = strings.TrimSpace(string(output))
+
+	version, err := parseVersion(info.Raw)
+	if err != nil {
+		return err
+	}
+	info.Version = formatVersion(version)
+
+	if min := GetBFCMinVersion(); min != "" {
+		minVersion, err := parseVersion(min)
+		if err != nil {
+			return fmt.Errorf("配置 bfc.min_version 无效: %v", err)
+		}
+		if compareVersion(version, minVersion) < 0 {
+			return fmt.Errorf("编译器版本 %s 低于支持的最低版本 %s", info.Version, min)
+		}
+	}
+	if max := GetBFCMaxVersion(); max != "" {
+		maxVersion, err := parseVersion(max)
+		if err != nil {
+			return fmt.Errorf("配置 bfc.max_version 无效: %v", err)
+		}
+		if compareVersion(version, maxVersion) > 0 {
+			return fmt.Errorf("编译器版本 %s 高于支持的最高版本 %s", info.Version, max)
+		}
+	}
+
+	info.Supported = true
+	return nil
+}
+
+// parseVersion 从文本中解析出 major.minor.patch
+func parseVersion(text string) ([3]int, error) {
+	var version [3]int
+	matches := versionPattern.FindStringSubmatch(text)
+	if len(matches) != 4 {
+		return version, fmt.Errorf("无法解析版本号: %q", text)
+	}
+	for i := 0; i < 3; i++ {
+		n, err := strconv.Atoi(matches[i+1])
+		if err != nil {
+			return version, fmt.Errorf("无法解析版本号: %q", text)
+		}
+		version[i] = n
+	}
+	return version, nil
+}
+
+// compareVersion 比较两个版本号，返回 -1、0 或 1
+func compareVersion(a, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// formatVersion 格式化版本号
+func formatVersion(version [3]int) string {
+	return fmt.Sprintf("%d.%d.%d", version[0], version[1], version[2])
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 
 	"gopkg.in/yaml.v3"
@@ -15,31 +16,108 @@ type Config struct {
 	} `yaml:"server"`
 	CoinTemplatePath string `yaml:"coin_template_path"`
 	BFC              struct {
-		Directory  string `yaml:"directory"`
-		BinaryPath string `yaml:"binary_path"`
+		Directory       string `yaml:"directory"`
+		BinaryPath      string `yaml:"binary_path"`
+		MinVersion      string `yaml:"min_version"`
+		MaxVersion      string `yaml:"max_version"`
+		VersionMismatch string `yaml:"version_mismatch"`
 	} `yaml:"bfc"`
 	BenfenRPC struct {
-		URL        string `yaml:"url"`
-		Timeout    int    `yaml:"timeout"`
-		RetryCount int    `yaml:"retry_count"`
+		URL                 string   `yaml:"url"`
+		URLs                []string `yaml:"urls"`
+		Timeout             int      `yaml:"timeout"`
+		RetryCount          int      `yaml:"retry_count"`
+		HealthCheckInterval int      `yaml:"health_check_interval"`
+		MaxCheckpointLag    uint64   `yaml:"max_checkpoint_lag"`
+		// 录制和回放 RPC 流量，用于离线回归测试
+		RecordDir string   `yaml:"record_dir"`
+		ReplayDir string   `yaml:"replay_dir"`
+		Redact    []string `yaml:"redact"`
 	} `yaml:"benfen_rpc"`
+
+	// 多网络配置，请求通过 network 字段选择
+	Networks       map[string]NetworkConfig `yaml:"networks"`
+	DefaultNetwork string                   `yaml:"default_network"`
+
 	Database struct {
 		Host     string `yaml:"host"`
 		Port     int    `yaml:"port"`
 		Name     string `yaml:"name"`
 		User     string `yaml:"user"`
 		Password string `yaml:"password"`
+		SSLMode  string `yaml:"sslmode"`
 	} `yaml:"database"`
+	Webhooks struct {
+		MaxAttempts    int                   `yaml:"max_attempts"`
+		TimeoutSeconds int                   `yaml:"timeout_seconds"`
+		Subscriptions  []WebhookSubscription `yaml:"subscriptions"`
+	} `yaml:"webhooks"`
+	Tracker struct {
+		TimeoutSeconds int `yaml:"timeout_seconds"`
+	} `yaml:"tracker"`
+	Storage struct {
+		// Driver 存储驱动: sqlite（默认）、postgres（使用 database 配置）或 file
+		Driver     string `yaml:"driver"`
+		Path       string `yaml:"path"`
+		SQLitePath string `yaml:"sqlite_path"`
+	} `yaml:"storage"`
 	Cleanup struct {
 		IntervalMinutes  int `yaml:"interval_minutes"`
 		RetentionMinutes int `yaml:"retention_minutes"`
 	} `yaml:"cleanup"`
+	TokenOps struct {
+		MetadataPackage string `yaml:"metadata_package"`
+		CacheTTLSeconds int    `yaml:"cache_ttl_seconds"`
+	} `yaml:"token_ops"`
+	Gas struct {
+		DryRun              *bool `yaml:"dry_run"`
+		BudgetMarginPercent int   `yaml:"budget_margin_percent"`
+		CoinLockSeconds     int   `yaml:"coin_lock_seconds"`
+	} `yaml:"gas"`
+	Signer struct {
+		Enabled        bool   `yaml:"enabled"`
+		KeystorePath   string `yaml:"keystore_path"`
+		PassphraseEnv  string `yaml:"passphrase_env"`
+		PassphraseFile string `yaml:"passphrase_file"`
+	} `yaml:"signer"`
+	Admin struct {
+		Token string `yaml:"token"`
+	} `yaml:"admin"`
+	Audit struct {
+		Enabled               *bool  `yaml:"enabled"`
+		Path                  string `yaml:"path"`
+		KeyEnv                string `yaml:"key_env"`
+		AnchorIntervalSeconds int    `yaml:"anchor_interval_seconds"`
+	} `yaml:"audit"`
 	Log struct {
 		Level string `yaml:"level"`
 		File  string `yaml:"file"`
 	} `yaml:"log"`
 }
 
+// NetworkConfig 定义一个网络的 RPC 节点、链 ID、模板依赖版本和限制
+type NetworkConfig struct {
+	URLs    []string `yaml:"urls" json:"urls"`
+	ChainID string   `yaml:"chain_id" json:"chain_id,omitempty"`
+	// TemplateRevision 替换模板 Move.toml 中依赖的 rev，留空时保持模板原样
+	TemplateRevision string `yaml:"template_revision" json:"template_revision,omitempty"`
+	// MetadataPackage 该网络上 metadata::update_metadata 的合约包地址，留空时使用 token_ops.metadata_package
+	MetadataPackage string `yaml:"metadata_package" json:"metadata_package,omitempty"`
+	Limits          struct {
+		// MaxGasBudget 允许的最大 gas 预算，0 表示不限制
+		MaxGasBudget uint64 `yaml:"max_gas_budget" json:"max_gas_budget,omitempty"`
+		// MaxMintRecipients 单笔铸币交易最多的接收方数量，0 表示使用默认值
+		MaxMintRecipients int `yaml:"max_mint_recipients" json:"max_mint_recipients,omitempty"`
+	} `yaml:"limits" json:"limits"`
+}
+
+// WebhookSubscription 定义一个 webhook 订阅
+type WebhookSubscription struct {
+	URL    string   `yaml:"url" json:"url"`
+	Secret string   `yaml:"secret" json:"-"`
+	Events []string `yaml:"events" json:"events"`
+}
+
 // 全局配置变量
 var AppConfig *Config
 
@@ -93,6 +171,30 @@ func GetBFCBinaryPath() string {
 	return "/usr/local/bfc/bfc" // 默认值
 }
 
+// GetBFCMinVersion 获取支持的最低编译器版本（包含）
+func GetBFCMinVersion() string {
+	if AppConfig != nil {
+		return AppConfig.BFC.MinVersion
+	}
+	return "" // 默认不限制
+}
+
+// GetBFCMaxVersion 获取支持的最高编译器版本（包含）
+func GetBFCMaxVersion() string {
+	if AppConfig != nil {
+		return AppConfig.BFC.MaxVersion
+	}
+	return "" // 默认不限制
+}
+
+// GetBFCVersionMismatchMode 获取编译器版本不匹配时的处理方式: refuse 或 degraded
+func GetBFCVersionMismatchMode() string {
+	if AppConfig != nil && AppConfig.BFC.VersionMismatch != "" {
+		return AppConfig.BFC.VersionMismatch
+	}
+	return "refuse" // 默认拒绝启动
+}
+
 // GetBenfenRPCURL 获取 Benfen RPC URL
 func GetBenfenRPCURL() string {
 	if AppConfig != nil {
@@ -101,6 +203,14 @@ func GetBenfenRPCURL() string {
 	return "https://rpc.benfen.org" // 默认值
 }
 
+// GetBenfenRPCURLs 获取所有 Benfen RPC 节点地址，未配置 urls 时使用 url
+func GetBenfenRPCURLs() []string {
+	if AppConfig != nil && len(AppConfig.BenfenRPC.URLs) > 0 {
+		return AppConfig.BenfenRPC.URLs
+	}
+	return []string{GetBenfenRPCURL()}
+}
+
 // GetBenfenRPCTimeout 获取 Benfen RPC 超时时间
 func GetBenfenRPCTimeout() int {
 	if AppConfig != nil {
@@ -109,14 +219,302 @@ func GetBenfenRPCTimeout() int {
 	return 30 // 默认值
 }
 
-// GetBenfenRPCRetryCount 获取 Benfen RPC 重试次数
+// GetBenfenRPCRetryCount 获取 Benfen RPC 重试次数，限制在 0 到 maxBenfenRPCRetryCount 之间
 func GetBenfenRPCRetryCount() int {
 	if AppConfig != nil {
-		return AppConfig.BenfenRPC.RetryCount
+		switch count := AppConfig.BenfenRPC.RetryCount; {
+		case count < 0:
+			return 0
+		case count > maxBenfenRPCRetryCount:
+			return maxBenfenRPCRetryCount
+		default:
+			return count
+		}
 	}
 	return 3 // 默认重试3次
 }
 
+// maxBenfenRPCRetryCount 重试轮数上限，每轮最多等待 5 秒
+const maxBenfenRPCRetryCount = 10
+
+// GetBenfenRPCHealthCheckInterval 获取节点健康检查间隔（秒）
+func GetBenfenRPCHealthCheckInterval() int {
+	if AppConfig != nil && AppConfig.BenfenRPC.HealthCheckInterval > 0 {
+		return AppConfig.BenfenRPC.HealthCheckInterval
+	}
+	return 30 // 默认30秒
+}
+
+// GetBenfenRPCMaxCheckpointLag 获取节点允许落后的最大检查点数
+func GetBenfenRPCMaxCheckpointLag() uint64 {
+	if AppConfig != nil && AppConfig.BenfenRPC.MaxCheckpointLag > 0 {
+		return AppConfig.BenfenRPC.MaxCheckpointLag
+	}
+	return 20 // 默认20个检查点
+}
+
+// GetBenfenRPCRecordDir 获取 RPC 流量录制目录，为空时不录制
+func GetBenfenRPCRecordDir() string {
+	if AppConfig != nil {
+		return AppConfig.BenfenRPC.RecordDir
+	}
+	return ""
+}
+
+// GetBenfenRPCReplayDir 获取 RPC 流量回放目录，不为空时不访问节点
+func GetBenfenRPCReplayDir() string {
+	if AppConfig != nil {
+		return AppConfig.BenfenRPC.ReplayDir
+	}
+	return ""
+}
+
+// GetBenfenRPCRedact 获取录制时需要脱敏的字符串
+func GetBenfenRPCRedact() []string {
+	if AppConfig != nil {
+		return AppConfig.BenfenRPC.Redact
+	}
+	return nil
+}
+
+// GetNetworks 获取全部网络配置，未配置 networks 时由 benfen_rpc 生成名为 default 的网络
+func GetNetworks() map[string]NetworkConfig {
+	if AppConfig != nil && len(AppConfig.Networks) > 0 {
+		return AppConfig.Networks
+	}
+	return map[string]NetworkConfig{
+		"default": {URLs: GetBenfenRPCURLs()},
+	}
+}
+
+// GetDefaultNetwork 获取请求未指定网络时使用的网络名称，只配置了一个网络时默认使用该网络
+func GetDefaultNetwork() string {
+	if AppConfig != nil && AppConfig.DefaultNetwork != "" {
+		return AppConfig.DefaultNetwork
+	}
+	networks := GetNetworks()
+	if len(networks) == 1 {
+		for name := range networks {
+			return name
+		}
+	}
+	return "default"
+}
+
+// GetMetadataPackage 获取提供 metadata::update_metadata 的合约包地址
+func GetMetadataPackage() string {
+	if AppConfig != nil && AppConfig.TokenOps.MetadataPackage != "" {
+		return AppConfig.TokenOps.MetadataPackage
+	}
+	return "0xb405c1c029e436eac6ece68269c820528b9a28ee5615c7632bf70b5a6d705e72" // 默认值
+}
+
+// GetTokenCacheTTLSeconds 获取代币查询结果的缓存时间
+func GetTokenCacheTTLSeconds() int {
+	if AppConfig != nil && AppConfig.TokenOps.CacheTTLSeconds > 0 {
+		return AppConfig.TokenOps.CacheTTLSeconds
+	}
+	return 10 // 默认10秒
+}
+
+// IsGasDryRunEnabled 是否在返回发布交易前预执行估算 gas
+func IsGasDryRunEnabled() bool {
+	if AppConfig != nil && AppConfig.Gas.DryRun != nil {
+		return *AppConfig.Gas.DryRun
+	}
+	return true // 默认开启
+}
+
+// GetGasBudgetMarginPercent 获取推荐 gas 预算相对预估消耗的上浮比例（百分比）
+func GetGasBudgetMarginPercent() int {
+	if AppConfig != nil && AppConfig.Gas.BudgetMarginPercent > 0 {
+		return AppConfig.Gas.BudgetMarginPercent
+	}
+	return 20 // 默认上浮20%
+}
+
+// GetGasCoinLockSeconds 获取自动选择的 gas 币被锁定的时长（秒）
+func GetGasCoinLockSeconds() int {
+	if AppConfig != nil && AppConfig.Gas.CoinLockSeconds > 0 {
+		return AppConfig.Gas.CoinLockSeconds
+	}
+	return 120 // 默认锁定2分钟
+}
+
+// IsSignerEnabled 是否启用服务端签名
+func IsSignerEnabled() bool {
+	if AppConfig != nil {
+		return AppConfig.Signer.Enabled
+	}
+	return false // 默认关闭
+}
+
+// GetSignerKeystorePath 获取加密密钥文件路径
+func GetSignerKeystorePath() string {
+	if AppConfig != nil {
+		return AppConfig.Signer.KeystorePath
+	}
+	return ""
+}
+
+// GetSignerPassphraseEnv 获取存放密钥口令的环境变量名
+func GetSignerPassphraseEnv() string {
+	if AppConfig != nil && AppConfig.Signer.PassphraseEnv != "" {
+		return AppConfig.Signer.PassphraseEnv
+	}
+	return "OBC_KEYSTORE_PASSPHRASE" // 默认环境变量
+}
+
+// GetSignerPassphraseFile 获取存放密钥口令的文件路径，优先于环境变量
+func GetSignerPassphraseFile() string {
+	if AppConfig != nil {
+		return AppConfig.Signer.PassphraseFile
+	}
+	return ""
+}
+
+// IsAuditEnabled 是否记录审计日志
+func IsAuditEnabled() bool {
+	if AppConfig != nil && AppConfig.Audit.Enabled != nil {
+		return *AppConfig.Audit.Enabled
+	}
+	return true // 默认开启
+}
+
+// GetAuditPath 获取审计日志文件路径
+func GetAuditPath() string {
+	if AppConfig != nil && AppConfig.Audit.Path != "" {
+		return AppConfig.Audit.Path
+	}
+	return "./data/audit.log" // 默认值
+}
+
+// GetAuditKeyEnv 获取存放审计日志 HMAC 密钥的环境变量名
+func GetAuditKeyEnv() string {
+	if AppConfig != nil && AppConfig.Audit.KeyEnv != "" {
+		return AppConfig.Audit.KeyEnv
+	}
+	return "OBC_AUDIT_KEY" // 默认环境变量
+}
+
+// GetAuditAnchorIntervalSeconds 获取把审计日志最新哈希写入服务日志的间隔（秒）
+func GetAuditAnchorIntervalSeconds() int {
+	if AppConfig != nil && AppConfig.Audit.AnchorIntervalSeconds > 0 {
+		return AppConfig.Audit.AnchorIntervalSeconds
+	}
+	return 300 // 默认 5 分钟
+}
+
+// GetAdminToken 获取管理接口访问令牌，为空时管理接口不可用
+func GetAdminToken() string {
+	if AppConfig != nil {
+		return AppConfig.Admin.Token
+	}
+	return ""
+}
+
+// GetWebhookSubscriptions 获取 webhook 订阅列表
+func GetWebhookSubscriptions() []WebhookSubscription {
+	if AppConfig != nil {
+		return AppConfig.Webhooks.Subscriptions
+	}
+	return nil
+}
+
+// GetWebhookMaxAttempts 获取 webhook 最大投递次数，超过后进入死信列表
+func GetWebhookMaxAttempts() int {
+	if AppConfig != nil && AppConfig.Webhooks.MaxAttempts > 0 {
+		return AppConfig.Webhooks.MaxAttempts
+	}
+	return 5 // 默认5次
+}
+
+// GetWebhookTimeoutSeconds 获取单次 webhook 投递超时时间（秒）
+func GetWebhookTimeoutSeconds() int {
+	if AppConfig != nil && AppConfig.Webhooks.TimeoutSeconds > 0 {
+		return AppConfig.Webhooks.TimeoutSeconds
+	}
+	return 10 // 默认10秒
+}
+
+// GetTrackerTimeoutSeconds 获取交易确认跟踪的超时时间（秒）
+func GetTrackerTimeoutSeconds() int {
+	if AppConfig != nil && AppConfig.Tracker.TimeoutSeconds > 0 {
+		return AppConfig.Tracker.TimeoutSeconds
+	}
+	return 300 // 默认5分钟
+}
+
+// GetStoragePath 获取代币记录存储文件路径
+func GetStoragePath() string {
+	if AppConfig != nil && AppConfig.Storage.Path != "" {
+		return AppConfig.Storage.Path
+	}
+	return "./data/tokens.json" // 默认值
+}
+
+// GetStorageDriver 获取存储驱动
+func GetStorageDriver() string {
+	if AppConfig != nil && AppConfig.Storage.Driver != "" {
+		return AppConfig.Storage.Driver
+	}
+	return "sqlite" // 默认值
+}
+
+// GetStorageSQLitePath 获取 SQLite 数据库文件路径
+func GetStorageSQLitePath() string {
+	if AppConfig != nil && AppConfig.Storage.SQLitePath != "" {
+		return AppConfig.Storage.SQLitePath
+	}
+	return "./data/tokens.db" // 默认值
+}
+
+// GetDatabaseHost 获取 PostgreSQL 主机
+func GetDatabaseHost() string {
+	if AppConfig != nil && AppConfig.Database.Host != "" {
+		return AppConfig.Database.Host
+	}
+	return "localhost" // 默认值
+}
+
+// GetDatabasePort 获取 PostgreSQL 端口
+func GetDatabasePort() int {
+	if AppConfig != nil && AppConfig.Database.Port > 0 {
+		return AppConfig.Database.Port
+	}
+	return 5432 // 默认值
+}
+
+// GetDatabaseName 获取 PostgreSQL 数据库名
+func GetDatabaseName() string {
+	if AppConfig != nil && AppConfig.Database.Name != "" {
+		return AppConfig.Database.Name
+	}
+	return "obc_coin_db" // 默认值
+}
+
+// GetDatabaseSSLMode 获取 PostgreSQL sslmode
+func GetDatabaseSSLMode() string {
+	if AppConfig != nil && AppConfig.Database.SSLMode != "" {
+		return AppConfig.Database.SSLMode
+	}
+	return "disable" // 默认值
+}
+
+// GetDatabaseURL 由 database 配置生成 PostgreSQL 连接串
+func GetDatabaseURL() string {
+	u := url.URL{
+		Scheme: "postgres",
+		Host:   fmt.Sprintf("%s:%d", GetDatabaseHost(), GetDatabasePort()),
+		Path:   "/" + GetDatabaseName(),
+	}
+	if AppConfig != nil && AppConfig.Database.User != "" {
+		u.User = url.UserPassword(AppConfig.Database.User, AppConfig.Database.Password)
+	}
+	u.RawQuery = url.Values{"sslmode": {GetDatabaseSSLMode()}}.Encode()
+	return u.String()
+}
+
 // GetCleanupIntervalMinutes 获取清理任务执行间隔（分钟）
 func GetCleanupIntervalMinutes() int {
 	if AppConfig != nil {
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 确认令牌有效期
+const confirmationTTL = 5 * time.Minute
+
+// confirmationKey 签发确认令牌的密钥，每次启动随机生成，重启后旧令牌失效
+var confirmationKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// issueConfirmation 为不可逆操作签发确认令牌，令牌绑定网络、操作参数和过期时间
+func issueConfirmation(network, action string, params ...string) (string, time.Time) {
+	expires := time.Now().Add(confirmationTTL).Truncate(time.Second)
+	expiry := strconv.FormatInt(expires.Unix(), 10)
+	return expiry + "." + signConfirmation(expiry, network, action, params), expires
+}
+
+// verifyConfirmation 校验确认令牌是否由本服务为相同网络和参数签发且未过期
+func verifyConfirmation(token, network, action string, params ...string) error {
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return fmt.Errorf("确认令牌格式无效")
+	}
+	unix, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil {
+		return fmt.Errorf("确认令牌格式无效")
+	}
+	if !hmac.Equal([]byte(signature), []byte(signConfirmation(expiry, network, action, params))) {
+		return fmt.Errorf("确认令牌与本次操作参数不符")
+	}
+	if time.Now().After(time.Unix(unix, 0)) {
+		return fmt.Errorf("确认令牌已过期")
+	}
+	return nil
+}
+
+// signConfirmation 计算 hex(HMAC-SHA256(key, expiry|network|action|params...))
+func signConfirmation(expiry, network, action string, params []string) string {
+	mac := hmac.New(sha256.New, confirmationKey)
+	mac.Write([]byte(strings.Join(append([]string{expiry, network, action}, params...), "|")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// requireConfirmation 校验请求中的确认令牌，令牌绑定 r 所选的网络，缺失或无效时返回 428 和新签发的令牌。
+// 校验失败时已写出响应并返回 false。
+func requireConfirmation(w http.ResponseWriter, r *http.Request, token, action, description string, params ...string) bool {
+	network := networkFrom(r.Context()).Name
+	message := "该操作不可撤销，请确认后携带 confirmation_token 重新提交"
+	if token != "" {
+		err := verifyConfirmation(token, network, action, params...)
+		if err == nil {
+			return true
+		}
+		message = fmt.Sprintf("%v，请使用新的 confirmation_token 重新提交", err)
+	}
+
+	issued, expires := issueConfirmation(network, action, params...)
+	writeResponse(w, http.StatusPreconditionRequired, TokenResponse{
+		Success: false,
+		Message: message,
+		Data: map[string]interface{}{
+			"action":             action,
+			"network":            network,
+			"description":        description,
+			"confirmation_token": issued,
+			"expires_at":         expires,
+		},
+	})
+	return false
+}
@@ -0,0 +1,16 @@
+package main
+
+import "testing"
+
+// 确认令牌绑定网络，为 devnet 签发的令牌不能确认 mainnet 上的同一操作
+func TestConfirmationBoundToNetwork(t *testing.T) {
+	params := []string{testSender, "0x1::a::A", "0x5"}
+	token, _ := issueConfirmation("devnet", TxKindMetadataFreeze, params...)
+
+	if err := verifyConfirmation(token, "devnet", TxKindMetadataFreeze, params...); err != nil {
+		t.Fatalf("同一网络的令牌应校验通过: %v", err)
+	}
+	if err := verifyConfirmation(token, "mainnet", TxKindMetadataFreeze, params...); err == nil {
+		t.Fatalf("其他网络不应接受该令牌")
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"obc_coin_api/benfen"
+)
+
+// 分页查询币列表时每页数量
+const coinPageLimit = 50
+
+// GasCoinSelection 自动选择的 gas 币
+type GasCoinSelection struct {
+	ObjectID     string `json:"object_id"`
+	Balance      uint64 `json:"balance,string"`
+	AutoSelected bool   `json:"auto_selected"`
+}
+
+// GasMergePlan 单个币不足以支付预算时的合并方案
+type GasMergePlan struct {
+	Primary  string   `json:"primary"`
+	Merge    []string `json:"merge"`
+	Total    uint64   `json:"total,string"`
+	Required uint64   `json:"required,string"`
+}
+
+// GasSelectionError gas 币选择失败
+type GasSelectionError struct {
+	Message string
+	Plan    *GasMergePlan
+}
+
+func (e *GasSelectionError) Error() string {
+	return e.Message
+}
+
+// gasCoinLocker 记录已被进行中的发布占用的 gas 币
+type gasCoinLocker struct {
+	mu    sync.Mutex
+	locks map[string]time.Time
+}
+
+// 全局 gas 币锁
+var gasCoinLocks = &gasCoinLocker{locks: make(map[string]time.Time)}
+
+// reserve 从候选币中选择余额足够的最小单个币并锁定，不足时返回合并方案或余额不足错误
+func (l *gasCoinLocker) reserve(coins []benfen.Coin, budget uint64) (*benfen.Coin, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 删除已过期的锁，否则曾经锁定过的币会一直留在表中
+	now := time.Now()
+	for id, expiry := range l.locks {
+		if !now.Before(expiry) {
+			delete(l.locks, id)
+		}
+	}
+
+	var available []benfen.Coin
+	var total uint64
+	for _, coin := range coins {
+		if _, locked := l.locks[coin.CoinObjectID]; locked {
+			continue
+		}
+		available = append(available, coin)
+		total += uint64(coin.Balance)
+	}
+
+	// 按余额升序，取第一个能覆盖预算的币
+	sort.Slice(available, func(i, j int) bool {
+		return available[i].Balance < available[j].Balance
+	})
+	for i := range available {
+		if uint64(available[i].Balance) >= budget {
+			l.locks[available[i].CoinObjectID] = now.Add(time.Duration(GetGasCoinLockSeconds()) * time.Second)
+			return &available[i], nil
+		}
+	}
+
+	if total < budget {
+		return nil, &GasSelectionError{
+			Message: fmt.Sprintf("BFC 余额不足: 可用 %d, 需要 %d", total, budget),
+		}
+	}
+
+	// 按余额降序累加，得到最少数量的合并方案
+	plan := &GasMergePlan{Required: budget}
+	for i := len(available) - 1; i >= 0 && plan.Total < budget; i-- {
+		if plan.Primary == "" {
+			plan.Primary = available[i].CoinObjectID
+		} else {
+			plan.Merge = append(plan.Merge, available[i].CoinObjectID)
+		}
+		plan.Total += uint64(available[i].Balance)
+	}
+	return nil, &GasSelectionError{
+		Message: fmt.Sprintf("没有单个 gas 币足以支付预算 %d，需要先合并 %d 个币", budget, len(plan.Merge)+1),
+		Plan:    plan,
+	}
+}
+
+// release 释放 gas 币锁
+func (l *gasCoinLocker) release(objectIDs ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range objectIDs {
+		delete(l.locks, id)
+	}
+}
+
+// selectGasCoin 查询发送者的 BFC 币并选择可支付预算的 gas 币
+func selectGasCoin(ctx context.Context, owner string, budget uint64) (*benfen.Coin, error) {
+	coins, err := fetchAllCoins(ctx, owner, benfen.BFCCoinType)
+	if err != nil {
+		return nil, fmt.Errorf("查询 gas 币失败: %w", err)
+	}
+	return gasCoinLocks.reserve(coins, budget)
+}
+
+// gasCoinBalance 查询发送者指定 BFC 币的余额
+func gasCoinBalance(ctx context.Context, owner, objectID string) (uint64, error) {
+	coins, err := fetchAllCoins(ctx, owner, benfen.BFCCoinType)
+	if err != nil {
+		return 0, err
+	}
+	for _, coin := range coins {
+		if coin.CoinObjectID == objectID {
+			return uint64(coin.Balance), nil
+		}
+	}
+	return 0, fmt.Errorf("发送者 %s 没有 BFC 币 %s", owner, objectID)
+}
+
+// fetchAllCoins 分页查询地址持有的指定类型的全部币
+func fetchAllCoins(ctx context.Context, owner, coinType string) ([]benfen.Coin, error) {
+	var coins []benfen.Coin
+	var cursor *string
+	for {
+		var page benfen.CoinPage
+		if err := rpcFor(ctx).Call(ctx, benfen.MethodGetCoins, &page, owner, coinType, cursor, coinPageLimit); err != nil {
+			return nil, err
+		}
+		coins = append(coins, page.Data...)
+		if !page.HasNextPage || page.NextCursor == nil {
+			return coins, nil
+		}
+		cursor = page.NextCursor
+	}
+}
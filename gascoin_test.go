@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"obc_coin_api/benfen"
+)
+
+// 选择 gas 币时删除已过期的锁，锁表不会随锁定过的币一直增长
+func TestGasCoinLockerPrunesExpired(t *testing.T) {
+	locker := &gasCoinLocker{locks: make(map[string]time.Time)}
+	expired := time.Now().Add(-time.Second)
+	for i := 0; i < 100; i++ {
+		locker.locks[fmt.Sprintf("0x%x", i)] = expired
+	}
+	locker.locks["0xactive"] = time.Now().Add(time.Minute)
+
+	coins := []benfen.Coin{
+		{CoinObjectID: "0xactive", Balance: 1000},
+		{CoinObjectID: "0x1", Balance: 1000},
+	}
+	coin, err := locker.reserve(coins, 500)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if coin.CoinObjectID != "0x1" {
+		t.Fatalf("选择了 %s，应选择锁已过期的 0x1", coin.CoinObjectID)
+	}
+	if len(locker.locks) != 2 {
+		t.Fatalf("锁表中有 %d 项，应只剩未过期的锁和新锁定的币", len(locker.locks))
+	}
+}
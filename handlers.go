@@ -1,11 +1,14 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"io"
+	"io/fs"
 	"net/http"
 	"os"
 	"os/exec"
@@ -14,6 +17,10 @@ import (
 	"strings"
 	"time"
 	"unicode"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/address"
 )
 
 // TokenRequest 定义添加代币的请求结构
@@ -24,6 +31,8 @@ type TokenRequest struct {
 	Name        string `json:"name"`
 	CustomInfo  string `json:"custom_info"`
 	Description string `json:"description,omitempty"`
+	// Network 编译和发布使用的网络，留空时使用默认网络
+	Network string `json:"network,omitempty"`
 }
 
 // TokenResponse 定义响应结构
@@ -33,6 +42,13 @@ type TokenResponse struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// writeResponse 以 JSON 格式写出响应
+func writeResponse(w http.ResponseWriter, status int, response TokenResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
 // validateField 验证字段是否包含特殊字符和长度限制
 func validateField(field, fieldName string) error {
 	// 检查长度限制
@@ -50,7 +66,8 @@ func validateField(field, fieldName string) error {
 	return nil
 }
 
-// addToken 处理添加代币的请求
+// addToken 处理添加代币的请求。
+// 带 async=true 参数时立即返回 202 和 job_id，进度和结果通过 /api/token/jobs/{id}/events 获取。
 func addToken(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -66,106 +83,220 @@ func addToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	job := jobs.create()
+	w.Header().Set("X-Job-ID", job.ID)
+
+	if r.URL.Query().Get("async") == "true" {
+		go runAddToken(job, req)
+		writeResponse(w, http.StatusAccepted, TokenResponse{
+			Success: true,
+			Message: "任务已创建",
+			Data: map[string]interface{}{
+				"job_id":     job.ID,
+				"events_url": fmt.Sprintf("/api/token/jobs/%s/events", job.ID),
+			},
+		})
+		return
+	}
+
+	status, response := runAddToken(job, req)
+	writeResponse(w, status, response)
+}
+
+// validateTokenRequest 校验添加代币的请求参数
+func validateTokenRequest(req TokenRequest) error {
 	// 验证必填字段
 	if req.Symbol == "" || req.Name == "" {
-		response := TokenResponse{
-			Success: false,
-			Message: "Symbol 和 Name 字段不能为空",
-		}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return fmt.Errorf("Symbol 和 Name 字段不能为空")
 	}
 
 	// 验证 Symbol 字段
 	if err := validateField(req.Symbol, "Symbol"); err != nil {
-		response := TokenResponse{
-			Success: false,
-			Message: err.Error(),
-		}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return err
 	}
 
 	// 验证 Name 字段
 	if err := validateField(req.Name, "Name"); err != nil {
-		response := TokenResponse{
-			Success: false,
-			Message: err.Error(),
-		}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return err
 	}
 
-	// 校验 Decimal 不大于 1
+	// 校验 Decimal 不大于 10
 	if req.Decimal > 10 {
-		response := TokenResponse{
-			Success: false,
-			Message: "Decimal 不能大于 10",
-		}
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return fmt.Errorf("Decimal 不能大于 10")
+	}
+	return nil
+}
+
+// runAddToken 执行编译任务，返回 HTTP 状态码和响应
+func runAddToken(job *Job, req TokenRequest) (int, TokenResponse) {
+	record, compileOutput, status, err := compileToken(job, req)
+	if err != nil {
+		return failCompile(job, status, err)
+	}
+
+	compiler := GetCompilerInfo()
+	data := map[string]interface{}{
+		"job_id":     job.ID,
+		"compile_id": record.ID,
+		"network":    record.Network,
+		"request":    req,
+		// "output_file":    outputFile,
+		"compile_output":   compileOutput,
+		"modules":          record.Modules,
+		"dependencies":     record.Dependencies,
+		"compiler_version": record.CompilerVersion,
+	}
+	if compiler.Degraded {
+		data["compiler_warning"] = compiler.Message
+	}
+
+	return job.Complete(TokenResponse{
+		Success: true,
+		Message: "代币添加和编译成功",
+		Data:    data,
+	})
+}
+
+// failCompile 以编译失败结束任务，响应中同样包含编译器版本
+func failCompile(job *Job, status int, err error) (int, TokenResponse) {
+	return job.FailWith(status, TokenResponse{
+		Success: false,
+		Message: err.Error(),
+		Data: map[string]interface{}{
+			"job_id":           job.ID,
+			"compiler_version": GetCompilerInfo().Version,
+		},
+	})
+}
+
+// compileToken 执行校验、模板渲染和编译，各阶段进度写入任务事件。
+// 成功时返回已保存的编译记录和编译输出，失败时返回对应的 HTTP 状态码和错误。
+func compileToken(job *Job, req TokenRequest) (*TokenRecord, string, int, error) {
+	job.Emit(JobStageValidation, map[string]interface{}{"symbol": req.Symbol, "name": req.Name})
+
+	// 所有请求都保存记录，校验或编译失败时记录失败原因
+	record := newTokenRecord(req)
+	if err := validateTokenRequest(req); err != nil {
+		emitCompileFailed(record, err)
+		return nil, "", http.StatusBadRequest, err
 	}
+	network, err := resolveNetwork(req.Network)
+	if err != nil {
+		emitCompileFailed(record, err)
+		return nil, "", http.StatusBadRequest, err
+	}
+	req.Network = network.Name
+	record.Request.Network = network.Name
+	record.Network = network.Name
+	record.TemplateRevision = network.Config.TemplateRevision
 
 	// 处理模板文件替换
-	outputFile, err := processTemplate(req)
+	outputFile, err := processTemplate(req, network, job)
 	if err != nil {
-		response := TokenResponse{
-			Success: false,
-			Message: fmt.Sprintf("模板处理失败: %v", err),
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+		err = fmt.Errorf("模板处理失败: %v", err)
+		emitCompileFailed(record, err)
+		return nil, "", http.StatusInternalServerError, err
 	}
 
 	// 获取项目目录（复制的模板目录）
 	projectDir := filepath.Dir(filepath.Dir(outputFile)) // 从 sources/fast_coin_1.move 回到项目根目录
 
-	// 编译 Move 项目
-	compileOutput, err := compileMoveProject(projectDir)
+	// 编译 Move 项目，逐行推送编译输出
+	job.Emit(JobStageCompileStart, map[string]interface{}{"project_dir": filepath.Base(projectDir)})
+	compileOutput, err := compileMoveProject(projectDir, func(line string) {
+		job.Emit(JobStageCompileOutput, map[string]interface{}{"line": line})
+	})
 	if err != nil {
-		response := TokenResponse{
-			Success: false,
-			Message: fmt.Sprintf("编译失败: %v", err),
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
+		err = fmt.Errorf("编译失败: %v", err)
+		emitCompileFailed(record, err)
+		return nil, "", http.StatusInternalServerError, err
 	}
 
 	// 打印编译输出
 	log.Printf("编译输出:\n%s\n", compileOutput)
 
 	// 解析编译输出
+	job.Emit(JobStageParse, nil)
 	modules, dependencies, err := parseCompileOutput(compileOutput)
 	if err != nil {
-		response := TokenResponse{
-			Success: false,
-			Message: fmt.Sprintf("解析编译输出失败: %v", err),
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
-		return
-	}
+		err = fmt.Errorf("解析编译输出失败: %v", err)
+		emitCompileFailed(record, err)
+		return nil, "", http.StatusInternalServerError, err
+	}
+
+	// 记录编译结果，发布后用于关联链上对象
+	record.Status = TokenStatusCompiled
+	record.Modules = modules
+	record.Dependencies = dependencies
+	if err := tokenStore.SaveToken(record); err != nil {
+		log.Printf("保存代币记录失败: %v", err)
+	}
+	emitEvent(EventCompileSucceeded, map[string]interface{}{
+		"compile_id":       record.ID,
+		"network":          network.Name,
+		"symbol":           req.Symbol,
+		"name":             req.Name,
+		"compiler_version": record.CompilerVersion,
+	})
+
+	return record, compileOutput, http.StatusOK, nil
+}
 
-	response := TokenResponse{
-		Success: true,
-		Message: "代币添加和编译成功",
-		Data: map[string]interface{}{
-			"request": req,
-			// "output_file":    outputFile,
-			"compile_output": compileOutput,
-			"modules":        modules,
-			"dependencies":   dependencies,
-		},
+// newTokenRecord 为代币请求创建编译失败状态的记录，编译成功后再更新状态
+func newTokenRecord(req TokenRequest) *TokenRecord {
+	return &TokenRecord{
+		ID:              newID(),
+		Request:         req,
+		Network:         req.Network,
+		Status:          TokenStatusCompileFailed,
+		CompilerVersion: GetCompilerInfo().Version,
+		TemplateVersion: templateVersion(GetCoinTemplatePath()),
 	}
+}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(response)
+// emitCompileFailed 保存编译失败的记录并发送编译失败事件
+func emitCompileFailed(record *TokenRecord, err error) {
+	record.Error = err.Error()
+	if saveErr := tokenStore.SaveToken(record); saveErr != nil {
+		log.Printf("保存代币记录失败: %v", saveErr)
+	}
+	emitEvent(EventCompileFailed, map[string]interface{}{
+		"compile_id": record.ID,
+		"network":    record.Network,
+		"symbol":     record.Request.Symbol,
+		"name":       record.Request.Name,
+		"error":      err.Error(),
+	})
+}
+
+// templateVersion 计算模板目录内容的摘要，用于追溯编译使用的模板版本，读取失败时返回空
+func templateVersion(templateDir string) string {
+	hash := sha256.New()
+	err := filepath.WalkDir(templateDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// 编译产物不属于模板内容
+		if d.IsDir() && d.Name() == "build" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(templateDir, path)
+		fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(rel), len(content))
+		hash.Write(content)
+		return nil
+	})
+	if err != nil {
+		log.Printf("计算模板版本失败: %v", err)
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
 }
 
 func parseCompileOutput(compileOutput string) ([]string, []string, error) {
@@ -192,8 +323,8 @@ func parseCompileOutput(compileOutput string) ([]string, []string, error) {
 	return result.Modules, result.Dependencies, nil
 }
 
-// processTemplate 处理模板文件替换
-func processTemplate(req TokenRequest) (string, error) {
+// processTemplate 处理模板文件替换，并按网络配置替换模板依赖的版本
+func processTemplate(req TokenRequest, network *Network, job *Job) (string, error) {
 	// 获取原始模板目录路径
 	originalTemplatePath := GetCoinTemplatePath()
 
@@ -206,6 +337,13 @@ func processTemplate(req TokenRequest) (string, error) {
 	if err := copyDir(originalTemplatePath, newTemplatePath); err != nil {
 		return "", fmt.Errorf("复制模板目录失败: %v", err)
 	}
+	job.Emit(JobStageTemplateCopy, map[string]interface{}{"directory": newDirName})
+
+	if revision := network.Config.TemplateRevision; revision != "" {
+		if err := setTemplateRevision(newTemplatePath, revision); err != nil {
+			return "", err
+		}
+	}
 
 	// 获取模板文件路径（从原始目录读取）
 	templatePath := filepath.Join(originalTemplatePath, "sources", "fast_coin.move")
@@ -245,27 +383,53 @@ func processTemplate(req TokenRequest) (string, error) {
 	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
 		return "", fmt.Errorf("写入输出文件失败: %v", err)
 	}
+	job.Emit(JobStageRender, nil)
 
 	return outputFile, nil
 }
 
-// compileMoveProject 编译 Move 项目
-func compileMoveProject(projectDir string) (string, error) {
+// compileMoveProject 编译 Move 项目，每输出一行调用一次 onLine
+func compileMoveProject(projectDir string, onLine func(line string)) (string, error) {
 	// 获取 BFC 二进制文件路径
 	bfcBinaryPath := GetBFCBinaryPath()
 
-	// 构建命令
+	// 构建命令，标准输出和标准错误合并到同一个管道
 	cmd := exec.Command(bfcBinaryPath, "move", "build", "--dump-bytecode-as-base64")
 	cmd.Dir = projectDir
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("启动编译命令失败: %v", err)
+	}
+
+	// 逐行读取输出，字节码以 base64 输出在单行中，需要较大的缓冲区
+	var output strings.Builder
+	scanned := make(chan struct{})
+	go func() {
+		defer close(scanned)
+		scanner := bufio.NewScanner(pr)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			output.WriteString(line)
+			output.WriteString("\n")
+			onLine(line)
+		}
+		io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Wait()
+	pw.Close()
+	<-scanned
 
-	// 执行命令并获取输出
-	output, err := cmd.CombinedOutput()
 	if err != nil {
-	    log.Fatalf("编译命令输出: %s, %v", string(output), err)
-		return "", fmt.Errorf("编译失败: %v, 输出: %s", err, string(output))
+		log.Printf("编译命令输出: %s, %v", output.String(), err)
+		return "", fmt.Errorf("编译失败: %v, 输出: %s", err, output.String())
 	}
 
-	return string(output), nil
+	return output.String(), nil
 }
 
 // copyDir 递归复制目录
@@ -356,6 +520,12 @@ type PublishRequest struct {
 	Dependencies    []interface{} `json:"dependencies"`
 	Gas             string        `json:"gas,omitempty"`
 	GasBudget       string        `json:"gas_budget"`
+	// 是否按预执行得到的推荐预算重新构建交易
+	RebuildWithEstimate bool `json:"rebuild_with_estimate,omitempty"`
+	// 对应 /api/token/add 返回的 compile_id，用于记录发布结果
+	CompileID string `json:"compile_id,omitempty"`
+	// Network 留空时使用编译记录的网络，没有编译记录时使用默认网络
+	Network string `json:"network,omitempty"`
 }
 
 // publishToken 处理发布代币的请求，转发到 Benfen RPC
@@ -374,82 +544,83 @@ func publishToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 构建完整的 JSON-RPC 请求
-	rpcRequest := map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      "1",
-		"method":  "unsafe_publish",
-		"params":  []interface{}{req.Sender, req.CompiledModules, req.Dependencies, req.Gas, req.GasBudget},
-	}
-
-	// 序列化请求体
-	requestBody, err := json.Marshal(rpcRequest)
+	// 校验发送者地址并统一为 0x 格式
+	sender, err := address.Parse(req.Sender)
 	if err != nil {
-		response := TokenResponse{
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
 			Success: false,
-			Message: "序列化请求失败",
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+			Message: fmt.Sprintf("sender 地址无效: %v", err),
+		})
 		return
 	}
+	req.Sender = sender.Hex()
 
-	// 获取 Benfen RPC URL
-	rpcURL := GetBenfenRPCURL()
+	r, ok := selectCompileNetwork(w, r, req.Network, req.CompileID)
+	if !ok {
+		return
+	}
 
-	// 创建转发请求
-	httpReq, err := http.NewRequest("POST", rpcURL, bytes.NewBuffer(requestBody))
-	if err != nil {
-		response := TokenResponse{
+	if _, err := parseGasBudget(r.Context(), req.GasBudget); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
 			Success: false,
-			Message: "创建转发请求失败",
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+			Message: err.Error(),
+		})
 		return
 	}
 
-	// 设置请求头
-	httpReq.Header.Set("Accept", "*/*")
-	httpReq.Header.Set("Accept-Encoding", "gzip, deflate, br")
-	httpReq.Header.Set("Connection", "keep-alive")
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("User-Agent", "PostmanRuntime-ApipostRuntime/1.1.0")
-
-	// 发送请求
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	// 构建未签名的发布交易并预执行估算 gas
+	result, err := buildPublishTransaction(r.Context(), req)
 	if err != nil {
-		response := TokenResponse{
-			Success: false,
-			Message: fmt.Sprintf("转发请求失败: %v", err),
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+		writePublishError(w, err)
 		return
 	}
-	defer resp.Body.Close()
 
-	// 读取响应
-	respBody, err := io.ReadAll(resp.Body)
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "发布交易构建成功",
+		Data:    result,
+	})
+}
+
+// convertAddress 解析地址并返回 0x 和 BFC 两种格式
+func convertAddress(w http.ResponseWriter, r *http.Request) {
+	addr, err := address.Parse(chi.URLParam(r, "addr"))
 	if err != nil {
-		response := TokenResponse{
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
 			Success: false,
-			Message: "读取响应失败",
-		}
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(response)
+			Message: fmt.Sprintf("地址无效: %v", err),
+		})
 		return
 	}
 
-	// 设置响应状态码和头部
-	w.WriteHeader(resp.StatusCode)
-	for key, values := range resp.Header {
-		for _, value := range values {
-			w.Header().Add(key, value)
-		}
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "地址解析成功",
+		Data: map[string]string{
+			"hex": addr.Hex(),
+			"bfc": addr.BFC(),
+		},
+	})
+}
+
+// healthCheck 返回服务健康状态及编译器信息
+func healthCheck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	compiler := GetCompilerInfo()
+	status := "ok"
+	if compiler.Degraded {
+		status = "degraded"
 	}
 
-	// 直接转发响应体
-	w.Write(respBody)
+	response := TokenResponse{
+		Success: true,
+		Message: status,
+		Data: map[string]interface{}{
+			"status":   status,
+			"compiler": compiler,
+		},
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
 }
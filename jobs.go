@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// 任务阶段，即 SSE 事件类型
+const (
+	JobStageValidation    = "validation"
+	JobStageTemplateCopy  = "template_copy"
+	JobStageRender        = "render"
+	JobStageCompileStart  = "compile_start"
+	JobStageCompileOutput = "compile_output"
+	JobStageParse         = "parse"
+	JobStagePublish       = "publish"
+	JobStageDone          = "done"
+	JobStageError         = "error"
+)
+
+// SSE 心跳间隔，避免代理断开空闲连接
+const sseKeepAlive = 15 * time.Second
+
+// JobEvent 任务进度事件，ID 在任务内单调递增
+type JobEvent struct {
+	ID    int         `json:"id"`
+	Stage string      `json:"stage"`
+	Data  interface{} `json:"data,omitempty"`
+	Time  time.Time   `json:"time"`
+}
+
+// Job 一次代币编译任务，保存全部进度事件以便断线重连后续传
+type Job struct {
+	ID        string
+	CreatedAt time.Time
+
+	mu        sync.Mutex
+	events    []JobEvent
+	done      bool
+	changed   chan struct{}
+	updatedAt time.Time
+}
+
+// Emit 追加一个进度事件并通知所有订阅者
+func (j *Job) Emit(stage string, data interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done {
+		return
+	}
+	j.append(stage, data)
+}
+
+// Fail 以错误结束任务，返回对应的 HTTP 状态码和响应
+func (j *Job) Fail(status int, message string) (int, TokenResponse) {
+	return j.FailWith(status, TokenResponse{
+		Success: false,
+		Message: message,
+		Data:    map[string]interface{}{"job_id": j.ID},
+	})
+}
+
+// FailWith 以指定的错误响应结束任务
+func (j *Job) FailWith(status int, response TokenResponse) (int, TokenResponse) {
+	j.finish(JobStageError, map[string]interface{}{"status": status, "response": response})
+	return status, response
+}
+
+// Complete 以成功结束任务，返回对应的 HTTP 状态码和响应
+func (j *Job) Complete(response TokenResponse) (int, TokenResponse) {
+	j.finish(JobStageDone, map[string]interface{}{"status": http.StatusOK, "response": response})
+	return http.StatusOK, response
+}
+
+// finish 追加结束事件并标记任务完成
+func (j *Job) finish(stage string, data interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.done {
+		return
+	}
+	j.append(stage, data)
+	j.done = true
+}
+
+// append 追加事件并唤醒等待者，调用方需持有锁
+func (j *Job) append(stage string, data interface{}) {
+	now := time.Now()
+	j.events = append(j.events, JobEvent{
+		ID:    len(j.events) + 1,
+		Stage: stage,
+		Data:  data,
+		Time:  now,
+	})
+	j.updatedAt = now
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// since 返回 ID 大于 lastID 的事件、任务是否已结束，以及下次有新事件时会关闭的通道
+func (j *Job) since(lastID int) ([]JobEvent, bool, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if lastID < 0 {
+		lastID = 0
+	}
+	if lastID > len(j.events) {
+		lastID = len(j.events)
+	}
+	events := make([]JobEvent, len(j.events)-lastID)
+	copy(events, j.events[lastID:])
+	return events, j.done, j.changed
+}
+
+// jobRegistry 保存进行中和最近完成的任务
+type jobRegistry struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// 全局任务表
+var jobs = &jobRegistry{jobs: make(map[string]*Job)}
+
+// create 创建新任务
+func (r *jobRegistry) create() *Job {
+	now := time.Now()
+	job := &Job{
+		ID:        newID(),
+		CreatedAt: now,
+		changed:   make(chan struct{}),
+		updatedAt: now,
+	}
+	r.mu.Lock()
+	r.jobs[job.ID] = job
+	r.mu.Unlock()
+	return job
+}
+
+// get 按 ID 获取任务
+func (r *jobRegistry) get(id string) (*Job, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	job, ok := r.jobs[id]
+	return job, ok
+}
+
+// prune 删除已结束且超过保留时间的任务
+func (r *jobRegistry) prune(retention time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	removed := 0
+	for id, job := range r.jobs {
+		job.mu.Lock()
+		expired := job.done && time.Since(job.updatedAt) > retention
+		job.mu.Unlock()
+		if expired {
+			delete(r.jobs, id)
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Printf("清理任务: 共清理 %d 个过期编译任务", removed)
+	}
+}
+
+// jobEvents 以 Server-Sent Events 推送任务进度，支持通过 Last-Event-ID 续传
+func jobEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := jobs.get(chi.URLParam(r, "id"))
+	if !ok {
+		writeResponse(w, http.StatusNotFound, TokenResponse{
+			Success: false,
+			Message: "任务不存在或已过期",
+		})
+		return
+	}
+
+	// 浏览器重连时通过请求头携带最后收到的事件 ID，也支持查询参数
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	last, _ := strconv.Atoi(lastID)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	rc := http.NewResponseController(w)
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		events, done, changed := job.since(last)
+		for _, event := range events {
+			if err := writeSSE(w, event); err != nil {
+				return
+			}
+			last = event.ID
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+		if done {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-changed:
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeSSE 写出一条 SSE 事件
+func writeSSE(w http.ResponseWriter, event JobEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Stage, data)
+	return err
+}
@@ -0,0 +1,121 @@
+package keystore
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Scheme 签名方案
+type Scheme string
+
+// 支持的签名方案
+const (
+	SchemeEd25519   Scheme = "ed25519"
+	SchemeSecp256k1 Scheme = "secp256k1"
+)
+
+// flag 返回签名方案在序列化签名和地址中的标识字节
+func (s Scheme) flag() byte {
+	if s == SchemeSecp256k1 {
+		return 0x01
+	}
+	return 0x00
+}
+
+// Key 解密后的签名密钥
+type Key struct {
+	scheme    Scheme
+	ed25519   ed25519.PrivateKey
+	secp256k1 *secp256k1.PrivateKey
+}
+
+// NewKey 由 32 字节私钥创建签名密钥
+func NewKey(scheme Scheme, privateKey []byte) (*Key, error) {
+	if len(privateKey) != 32 {
+		return nil, fmt.Errorf("私钥长度必须为32字节，实际 %d", len(privateKey))
+	}
+	switch scheme {
+	case SchemeEd25519:
+		return &Key{scheme: scheme, ed25519: ed25519.NewKeyFromSeed(privateKey)}, nil
+	case SchemeSecp256k1:
+		return &Key{scheme: scheme, secp256k1: secp256k1.PrivKeyFromBytes(privateKey)}, nil
+	default:
+		return nil, fmt.Errorf("不支持的签名方案: %s", scheme)
+	}
+}
+
+// Generate 随机生成签名密钥
+func Generate(scheme Scheme) (*Key, error) {
+	privateKey := make([]byte, 32)
+	if _, err := rand.Read(privateKey); err != nil {
+		return nil, err
+	}
+	return NewKey(scheme, privateKey)
+}
+
+// Scheme 返回签名方案
+func (k *Key) Scheme() Scheme {
+	return k.scheme
+}
+
+// PublicKey 返回公钥，secp256k1 为 33 字节压缩格式
+func (k *Key) PublicKey() []byte {
+	if k.scheme == SchemeSecp256k1 {
+		return k.secp256k1.PubKey().SerializeCompressed()
+	}
+	return k.ed25519.Public().(ed25519.PublicKey)
+}
+
+// Address 返回 0x 开头的十六进制地址：blake2b256(flag || 公钥)
+func (k *Key) Address() string {
+	data := append([]byte{k.scheme.flag()}, k.PublicKey()...)
+	sum := blake2b.Sum256(data)
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+// SignTransaction 对 base64 编码的交易签名，返回 base64 编码的序列化签名：flag || 签名 || 公钥
+func (k *Key) SignTransaction(txBytes string) (string, error) {
+	tx, err := base64.StdEncoding.DecodeString(txBytes)
+	if err != nil {
+		return "", fmt.Errorf("无效的交易字节: %v", err)
+	}
+
+	// 交易意图前缀: scope=TransactionData, version=V0, app=Sui
+	message := append([]byte{0, 0, 0}, tx...)
+	digest := blake2b.Sum256(message)
+
+	var signature []byte
+	switch k.scheme {
+	case SchemeSecp256k1:
+		hash := sha256.Sum256(digest[:])
+		sig := ecdsa.Sign(k.secp256k1, hash[:])
+		r, s := sig.R(), sig.S()
+		var rBytes, sBytes [32]byte
+		r.PutBytes(&rBytes)
+		s.PutBytes(&sBytes)
+		signature = append(rBytes[:], sBytes[:]...)
+	default:
+		signature = ed25519.Sign(k.ed25519, digest[:])
+	}
+
+	serialized := []byte{k.scheme.flag()}
+	serialized = append(serialized, signature...)
+	serialized = append(serialized, k.PublicKey()...)
+	return base64.StdEncoding.EncodeToString(serialized), nil
+}
+
+// privateKeyBytes 返回 32 字节私钥
+func (k *Key) privateKeyBytes() []byte {
+	if k.scheme == SchemeSecp256k1 {
+		return k.secp256k1.Serialize()
+	}
+	return k.ed25519.Seed()
+}
@@ -0,0 +1,171 @@
+// Package keystore 提供加密存储的签名密钥，支持 ed25519 和 secp256k1
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt 默认参数
+const (
+	scryptN      = 1 << 18
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+// File 加密后的密钥文件格式
+type File struct {
+	Version    int       `json:"version"`
+	Scheme     Scheme    `json:"scheme"`
+	Address    string    `json:"address"`
+	KDF        string    `json:"kdf"`
+	KDFParams  kdfParams `json:"kdf_params"`
+	Cipher     string    `json:"cipher"`
+	Nonce      string    `json:"nonce"`
+	Ciphertext string    `json:"ciphertext"`
+}
+
+// kdfParams scrypt 参数
+type kdfParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+// ErrWrongPassphrase 口令错误或文件被篡改
+var ErrWrongPassphrase = errors.New("口令错误或密钥文件已损坏")
+
+// Encrypt 使用口令加密私钥
+func Encrypt(key *Key, passphrase string) (*File, error) {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	params := kdfParams{N: scryptN, R: scryptR, P: scryptP, Salt: hex.EncodeToString(salt)}
+
+	aead, err := newAEAD(passphrase, params)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	// 方案和地址作为附加数据参与认证，防止被替换
+	file := &File{
+		Version:   1,
+		Scheme:    key.Scheme(),
+		Address:   key.Address(),
+		KDF:       "scrypt",
+		KDFParams: params,
+		Cipher:    "aes-256-gcm",
+		Nonce:     hex.EncodeToString(nonce),
+	}
+	ciphertext := aead.Seal(nil, nonce, key.privateKeyBytes(), file.additionalData())
+	file.Ciphertext = hex.EncodeToString(ciphertext)
+	return file, nil
+}
+
+// Decrypt 使用口令解密私钥
+func (f *File) Decrypt(passphrase string) (*Key, error) {
+	if f.Version != 1 || f.KDF != "scrypt" || f.Cipher != "aes-256-gcm" {
+		return nil, fmt.Errorf("不支持的密钥文件格式: version=%d kdf=%s cipher=%s", f.Version, f.KDF, f.Cipher)
+	}
+
+	aead, err := newAEAD(passphrase, f.KDFParams)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hex.DecodeString(f.Nonce)
+	if err != nil || len(nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	ciphertext, err := hex.DecodeString(f.Ciphertext)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	privateKey, err := aead.Open(nil, nonce, ciphertext, f.additionalData())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	key, err := NewKey(f.Scheme, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if key.Address() != f.Address {
+		return nil, ErrWrongPassphrase
+	}
+	return key, nil
+}
+
+// additionalData 返回参与认证的附加数据
+func (f *File) additionalData() []byte {
+	return []byte(string(f.Scheme) + ":" + f.Address)
+}
+
+// Load 读取并解密密钥文件
+func Load(path, passphrase string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取密钥文件失败: %v", err)
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("解析密钥文件失败: %v", err)
+	}
+	return file.Decrypt(passphrase)
+}
+
+// Save 加密私钥并写入文件，文件已存在时返回错误
+func Save(path string, key *Key, passphrase string) error {
+	file, err := Encrypt(key, passphrase)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := out.Write(data); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// newAEAD 由口令派生密钥并创建 AES-GCM
+func newAEAD(passphrase string, params kdfParams) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("口令不能为空")
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 salt: %v", err)
+	}
+	derived, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, scryptKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
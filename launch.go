@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"obc_coin_api/address"
+)
+
+// LaunchRequest 定义一键编译并构建发布交易的请求结构
+type LaunchRequest struct {
+	TokenRequest
+	Sender              string `json:"sender"`
+	Gas                 string `json:"gas,omitempty"`
+	GasBudget           string `json:"gas_budget"`
+	RebuildWithEstimate bool   `json:"rebuild_with_estimate,omitempty"`
+}
+
+// launchToken 编译代币并直接使用服务端保存的字节码构建未签名的发布交易。
+// 带 async=true 参数时立即返回 202 和 job_id。
+func launchToken(w http.ResponseWriter, r *http.Request) {
+	var req LaunchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+
+	// 编译前先校验发布参数，避免无效请求占用编译资源；被拒绝的请求同样保存记录
+	sender, err := address.Parse(req.Sender)
+	if err != nil {
+		rejectLaunch(w, req, fmt.Errorf("sender 地址无效: %v", err))
+		return
+	}
+	req.Sender = sender.Hex()
+
+	network, err := resolveNetwork(req.Network)
+	if err != nil {
+		rejectLaunch(w, req, err)
+		return
+	}
+	r = r.WithContext(withNetwork(r.Context(), network))
+	req.Network = network.Name
+
+	if _, err := parseGasBudget(r.Context(), req.GasBudget); err != nil {
+		rejectLaunch(w, req, err)
+		return
+	}
+
+	job := jobs.create()
+	w.Header().Set("X-Job-ID", job.ID)
+
+	if r.URL.Query().Get("async") == "true" {
+		go runLaunch(withNetwork(context.Background(), networkFrom(r.Context())), job, req)
+		writeResponse(w, http.StatusAccepted, TokenResponse{
+			Success: true,
+			Message: "任务已创建",
+			Data: map[string]interface{}{
+				"job_id":     job.ID,
+				"events_url": fmt.Sprintf("/api/token/jobs/%s/events", job.ID),
+			},
+		})
+		return
+	}
+
+	status, response := runLaunch(r.Context(), job, req)
+	writeResponse(w, status, response)
+}
+
+// rejectLaunch 保存被拒绝的请求并返回 400
+func rejectLaunch(w http.ResponseWriter, req LaunchRequest, err error) {
+	record := newTokenRecord(req.TokenRequest)
+	if sender, parseErr := address.Parse(req.Sender); parseErr == nil {
+		record.Sender = sender.Hex()
+	}
+	emitCompileFailed(record, err)
+	writeResponse(w, http.StatusBadRequest, TokenResponse{
+		Success: false,
+		Message: err.Error(),
+	})
+}
+
+// runLaunch 编译代币并构建发布交易，返回 HTTP 状态码和响应
+func runLaunch(ctx context.Context, job *Job, req LaunchRequest) (int, TokenResponse) {
+	record, _, status, err := compileToken(job, req.TokenRequest)
+	if err != nil {
+		return failCompile(job, status, err)
+	}
+
+	// 使用服务端保存的字节码，客户端无法在编译和发布之间篡改
+	job.Emit(JobStagePublish, map[string]interface{}{"compile_id": record.ID, "sender": req.Sender})
+	publishReq := PublishRequest{
+		Sender:              req.Sender,
+		CompiledModules:     toInterfaceSlice(record.Modules),
+		Dependencies:        toInterfaceSlice(record.Dependencies),
+		Gas:                 req.Gas,
+		GasBudget:           req.GasBudget,
+		RebuildWithEstimate: req.RebuildWithEstimate,
+		CompileID:           record.ID,
+		Network:             record.Network,
+	}
+	publish, err := buildPublishTransaction(ctx, publishReq)
+	if err != nil {
+		status, response := publishErrorResponse(err)
+		data := map[string]interface{}{
+			"job_id":     job.ID,
+			"compile_id": record.ID,
+		}
+		if response.Data != nil {
+			data["details"] = response.Data
+		}
+		response.Data = data
+		return job.FailWith(status, response)
+	}
+
+	record.Sender = req.Sender
+	if err := tokenStore.SaveToken(record); err != nil {
+		log.Printf("更新代币记录失败 %s: %v", record.ID, err)
+	}
+
+	return job.Complete(TokenResponse{
+		Success: true,
+		Message: "代币编译和发布交易构建成功",
+		Data: map[string]interface{}{
+			"job_id":           job.ID,
+			"compile_id":       record.ID,
+			"compiler_version": record.CompilerVersion,
+			"publish":          publish,
+		},
+	})
+}
+
+// toInterfaceSlice 将字符串切片转换为 RPC 参数需要的 []interface{}
+func toInterfaceSlice(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
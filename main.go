@@ -11,6 +11,14 @@ import (
 )
 
 func main() {
+	// 命令行子命令
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// 加载配置文件
 	if err := LoadConfig("config.yaml"); err != nil {
 		log.Printf("加载配置文件失败: %v，使用默认配置", err)
@@ -25,13 +33,36 @@ func main() {
 	}
 	log.Printf("BFC 目录检查通过: %s", bfcDir)
 
+	// 检查编译器二进制及版本
+	checkCompiler()
+
+	// 打开代币记录存储
+	initStorage()
+
+	// 初始化 Benfen RPC 客户端
+	initNetworks()
+
+	// 加载服务端签名密钥
+	initSigner()
+
+	// 恢复跟踪未确认的交易
+	initTxTracker()
+
+	// 打开审计日志
+	initAuditLog()
+
 	r := chi.NewRouter()
 
 	// 基础中间件
 	r.Use(middleware.RequestID)
+	r.Use(PeerAddrMiddleware)
 	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
+	r.Use(AuditMiddleware)
+
+	// 健康检查
+	r.Get("/health", healthCheck)
 
 	// 设置路由
 	r.Route("/api", func(r chi.Router) {
@@ -39,6 +70,40 @@ func main() {
 			// 为 /add 路由添加限流中间件
 			r.With(TokenAddRateLimitMiddleware).Post("/add", addToken)
 			r.Post("/publish", publishToken)
+			r.With(TokenAddRateLimitMiddleware).Post("/launch", launchToken)
+			r.Get("/jobs/{id}/events", jobEvents)
+			r.Get("/{coinType}", getTokenInfo)
+			r.Get("/{coinType}/balance/{address}", getTokenBalance)
+			r.Post("/{coinType}/metadata", updateTokenMetadata)
+			r.Post("/{coinType}/mint", mintToken)
+			r.Post("/{coinType}/burn", burnToken)
+			r.Post("/{coinType}/treasury-cap/transfer", transferTreasuryCap)
+			r.Post("/{coinType}/metadata/freeze", freezeMetadata)
+		})
+
+		// 代币记录包含发送者和全部请求参数，与管理接口使用同样的认证
+		r.Route("/tokens", func(r chi.Router) {
+			r.Use(AdminAuthMiddleware)
+			r.Get("/", listTokens)
+			r.Get("/{id}", getTokenRecord)
+		})
+
+		r.Get("/address/{addr}", convertAddress)
+
+		r.Route("/tx", func(r chi.Router) {
+			r.Post("/execute", executeSignedTransaction)
+			r.Get("/{digest}/status", getTransactionStatus)
+		})
+
+		// 管理接口
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(AdminAuthMiddleware)
+			r.Get("/rpc/endpoints", rpcEndpointsStatus)
+			r.Get("/audit/head", auditHead)
+			r.Post("/token/publish", signAndPublishToken)
+			r.Get("/webhooks/deliveries", webhookDeliveries)
+			r.Get("/webhooks/dead-letters", webhookDeadLetters)
+			r.Post("/webhooks/dead-letters/{id}/retry", retryWebhookDeadLetter)
 		})
 	})
 
@@ -48,7 +113,6 @@ func main() {
 	log.Printf("代币模板路径: %s", GetCoinTemplatePath())
 	log.Printf("BFC 目录: %s", GetBFCDirectory())
 	log.Printf("BFC 二进制路径: %s", GetBFCBinaryPath())
-	log.Printf("Benfen RPC URL: %s", GetBenfenRPCURL())
 	log.Printf("Benfen RPC 超时: %d 秒", GetBenfenRPCTimeout())
 	log.Printf("Benfen RPC 重试次数: %d", GetBenfenRPCRetryCount())
 	
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/address"
+)
+
+// 单笔铸币交易默认最多的接收方数量，可按网络配置 limits.max_mint_recipients
+const mintMaxRecipients = 100
+
+// MintRecipient 铸币接收方，Amount 为按代币精度表示的可读数量
+type MintRecipient struct {
+	Address string `json:"address"`
+	Amount  string `json:"amount"`
+}
+
+// MintRequest 铸币请求
+type MintRequest struct {
+	TokenOpRequest
+	// TreasuryCapID 未指定时使用发布记录中的 TreasuryCap
+	TreasuryCapID string          `json:"treasury_cap_id,omitempty"`
+	Recipients    []MintRecipient `json:"recipients"`
+}
+
+// mintToken 构建调用 coin::mint_and_transfer 的交易，多个接收方时构建为批量交易
+func mintToken(w http.ResponseWriter, r *http.Request) {
+	coinType, err := parseCoinType(chi.URLParam(r, "coinType"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var req MintRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+
+	r, ok := prepareTokenOp(w, r, &req.TokenOpRequest)
+	if !ok {
+		return
+	}
+	if max := networkFrom(r.Context()).MaxMintRecipients(); len(req.Recipients) == 0 || len(req.Recipients) > max {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("recipients 数量必须在 1 到 %d 之间", max),
+		})
+		return
+	}
+
+	tokenID, ok := resolveTreasuryCap(r.Context(), w, coinType, &req.TreasuryCapID)
+	if !ok {
+		return
+	}
+
+	// 按链上元数据的精度换算数量
+	metadata, err := fetchCoinMetadata(r.Context(), coinType)
+	if err != nil {
+		writeCoinMetadataError(w, err)
+		return
+	}
+
+	var total uint64
+	recipients := make([]map[string]interface{}, len(req.Recipients))
+	calls := make([]MoveCall, len(req.Recipients))
+	for i, recipient := range req.Recipients {
+		addr, err := address.Parse(recipient.Address)
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, TokenResponse{
+				Success: false,
+				Message: fmt.Sprintf("第 %d 个接收方地址无效: %v", i+1, err),
+			})
+			return
+		}
+		amount, err := parseAmount(recipient.Amount, metadata.Decimals)
+		if err == nil {
+			total, err = addAmounts(total, amount)
+		}
+		if err != nil {
+			writeResponse(w, http.StatusBadRequest, TokenResponse{
+				Success: false,
+				Message: fmt.Sprintf("第 %d 个接收方数量无效: %v", i+1, err),
+			})
+			return
+		}
+
+		recipients[i] = map[string]interface{}{
+			"address":     addr.Hex(),
+			"amount":      formatAmount(amount, metadata.Decimals),
+			"base_amount": strconv.FormatUint(amount, 10),
+		}
+		calls[i] = MoveCall{
+			Package:  "0x2",
+			Module:   "coin",
+			Function: "mint_and_transfer",
+			TypeArgs: []string{coinType},
+			Args:     []interface{}{req.TreasuryCapID, strconv.FormatUint(amount, 10), addr.Hex()},
+		}
+	}
+
+	build := moveCallBuilder(req.Sender, calls[0])
+	if len(calls) > 1 {
+		build = batchBuilder(req.Sender, calls)
+	}
+	runTokenOp(w, r, req.TokenOpRequest, TxKindMint, tokenID, build, map[string]interface{}{
+		"coin_type":         coinType,
+		"decimals":          metadata.Decimals,
+		"treasury_cap_id":   req.TreasuryCapID,
+		"recipients":        recipients,
+		"total_amount":      formatAmount(total, metadata.Decimals),
+		"total_base_amount": strconv.FormatUint(total, 10),
+		"calls":             calls,
+	})
+}
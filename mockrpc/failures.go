@@ -0,0 +1,98 @@
+package mockrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Failure 一条脚本化的故障，按添加顺序匹配第一条方法相同的故障
+type Failure struct {
+	// Method 匹配的 JSON-RPC 方法，为空时匹配所有方法
+	Method string `json:"method,omitempty"`
+	// Times 触发次数，0 表示一直生效直到清除
+	Times int `json:"times,omitempty"`
+	// Code 和 Message 返回的 JSON-RPC 错误，Code 默认为 -32000
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+	// ExecutionError 非空时预执行和执行返回失败状态的交易效果，而不是 RPC 错误
+	ExecutionError string `json:"execution_error,omitempty"`
+	// HTTPStatus 非 0 时直接返回该 HTTP 状态码，模拟节点不可用
+	HTTPStatus int `json:"http_status,omitempty"`
+	// DelayMs 返回前的延迟，可与其他字段组合或单独模拟慢节点
+	DelayMs int `json:"delay_ms,omitempty"`
+}
+
+// Delay 返回前的延迟
+func (f Failure) Delay() time.Duration {
+	return time.Duration(f.DelayMs) * time.Millisecond
+}
+
+// failureScript 按顺序保存待触发的故障
+type failureScript struct {
+	mu       sync.Mutex
+	failures []*Failure
+}
+
+// add 追加故障
+func (s *failureScript) add(failures ...Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range failures {
+		failure := failures[i]
+		s.failures = append(s.failures, &failure)
+	}
+}
+
+// clear 清除全部故障
+func (s *failureScript) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = nil
+}
+
+// list 返回剩余的故障
+func (s *failureScript) list() []Failure {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	failures := make([]Failure, len(s.failures))
+	for i, failure := range s.failures {
+		failures[i] = *failure
+	}
+	return failures
+}
+
+// match 取出与方法匹配的故障，有次数限制的故障用完后移除
+func (s *failureScript) match(method string) (Failure, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, failure := range s.failures {
+		if failure.Method != "" && failure.Method != method {
+			continue
+		}
+		matched := *failure
+		if failure.Times > 0 {
+			failure.Times--
+			if failure.Times == 0 {
+				s.failures = append(s.failures[:i], s.failures[i+1:]...)
+			}
+		}
+		return matched, true
+	}
+	return Failure{}, false
+}
+
+// LoadFailures 从 JSON 文件读取故障脚本，文件内容为 Failure 数组
+func LoadFailures(path string) ([]Failure, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var failures []Failure
+	if err := json.Unmarshal(data, &failures); err != nil {
+		return nil, fmt.Errorf("解析故障脚本失败: %v", err)
+	}
+	return failures, nil
+}
@@ -0,0 +1,515 @@
+package mockrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"obc_coin_api/benfen"
+)
+
+// 查询币的默认分页大小
+const defaultCoinPageLimit = 50
+
+// bfcMetadata 原生 BFC 的元数据
+var bfcMetadata = benfen.CoinMetadata{
+	Decimals: 9,
+	Name:     "Benfen Coin",
+	Symbol:   "BFC",
+}
+
+// params 按位置解析 JSON-RPC 参数
+type params []json.RawMessage
+
+// decode 解析第 i 个参数，缺失或为 null 时保持零值
+func (p params) decode(i int, value interface{}) *rpcError {
+	if i >= len(p) || string(p[i]) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(p[i], value); err != nil {
+		return newRPCError(codeInvalidParams, "第 %d 个参数无效: %v", i+1, err)
+	}
+	return nil
+}
+
+// address 解析地址或对象 ID 参数
+func (p params) address(i int) (string, *rpcError) {
+	var text string
+	if err := p.decode(i, &text); err != nil {
+		return "", err
+	}
+	id, err := normalizeID(text)
+	if err != nil {
+		return "", newRPCError(codeInvalidParams, "第 %d 个参数不是有效的地址: %v", i+1, err)
+	}
+	return id, nil
+}
+
+// coinType 解析币类型参数，缺失时为 BFC
+func (p params) coinType(i int) (string, *rpcError) {
+	var coinType string
+	if err := p.decode(i, &coinType); err != nil {
+		return "", err
+	}
+	if coinType == "" {
+		return benfen.BFCCoinType, nil
+	}
+	return normalizeCoinType(coinType), nil
+}
+
+// dispatch 分发 JSON-RPC 方法，executionError 非空时预执行和执行直接返回该失败
+func (s *Server) dispatch(req rpcRequest, executionError string) (interface{}, *rpcError) {
+	p := params(req.Params)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch req.Method {
+	case benfen.MethodGetChainID:
+		return s.opts.ChainID, nil
+	case benfen.MethodLatestCheckpoint:
+		return strconv.FormatUint(s.ledger.Checkpoint, 10), nil
+	case benfen.MethodGetCoins:
+		return s.getCoins(p)
+	case benfen.MethodGetBalance:
+		return s.getBalance(p)
+	case benfen.MethodGetCoinMetadata:
+		return s.getCoinMetadata(p)
+	case benfen.MethodGetTotalSupply:
+		return s.getTotalSupply(p)
+	case benfen.MethodGetObject:
+		return s.getObject(p)
+	case benfen.MethodGetTransaction:
+		return s.getTransaction(p)
+	case benfen.MethodUnsafePublish:
+		return s.buildPublish(p)
+	case benfen.MethodUnsafeMoveCall:
+		return s.buildMoveCall(p)
+	case benfen.MethodUnsafeBatch:
+		return s.buildBatch(p)
+	case benfen.MethodUnsafeTransfer:
+		return s.buildTransfer(p)
+	case benfen.MethodDryRun:
+		var txBytes string
+		if err := p.decode(0, &txBytes); err != nil {
+			return nil, err
+		}
+		return s.execute(txBytes, executionError, false)
+	case benfen.MethodExecute:
+		return s.executeSigned(p, executionError)
+	default:
+		return nil, newRPCError(codeMethodNotFound, "模拟节点不支持的方法: %s", req.Method)
+	}
+}
+
+// getCoins bfcx_getCoins(owner, coinType, cursor, limit)，游标为上一页最后一个币的对象 ID
+func (s *Server) getCoins(p params) (interface{}, *rpcError) {
+	owner, err := p.address(0)
+	if err != nil {
+		return nil, err
+	}
+	coinType, err := p.coinType(1)
+	if err != nil {
+		return nil, err
+	}
+	var cursor string
+	if err := p.decode(2, &cursor); err != nil {
+		return nil, err
+	}
+	limit := defaultCoinPageLimit
+	if err := p.decode(3, &limit); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultCoinPageLimit
+	}
+
+	page := benfen.CoinPage{Data: []benfen.Coin{}}
+	for _, coin := range s.ledger.coins(owner, coinType) {
+		if cursor != "" && coin.ID <= cursor {
+			continue
+		}
+		if len(page.Data) == limit {
+			page.HasNextPage = true
+			break
+		}
+		ref := coin.ref()
+		page.Data = append(page.Data, benfen.Coin{
+			CoinType:     coinType,
+			CoinObjectID: coin.ID,
+			Version:      ref.Version,
+			Digest:       ref.Digest,
+			Balance:      benfen.Uint64(coin.Balance),
+		})
+	}
+	if len(page.Data) > 0 {
+		next := page.Data[len(page.Data)-1].CoinObjectID
+		page.NextCursor = &next
+	}
+	return page, nil
+}
+
+// getBalance bfcx_getBalance(owner, coinType)
+func (s *Server) getBalance(p params) (interface{}, *rpcError) {
+	owner, err := p.address(0)
+	if err != nil {
+		return nil, err
+	}
+	coinType, err := p.coinType(1)
+	if err != nil {
+		return nil, err
+	}
+	balance := benfen.Balance{CoinType: coinType}
+	for _, coin := range s.ledger.coins(owner, coinType) {
+		balance.CoinObjectCount++
+		balance.TotalBalance += benfen.Uint64(coin.Balance)
+	}
+	return balance, nil
+}
+
+// getCoinMetadata bfcx_getCoinMetadata(coinType)，未知的币类型返回 null
+func (s *Server) getCoinMetadata(p params) (interface{}, *rpcError) {
+	coinType, err := p.coinType(0)
+	if err != nil {
+		return nil, err
+	}
+	if coinType == benfen.BFCCoinType {
+		return bfcMetadata, nil
+	}
+	if obj := s.findByType("0x2::coin::CoinMetadata<" + coinType + ">"); obj != nil {
+		return obj.Meta, nil
+	}
+	return nil, nil
+}
+
+// getTotalSupply bfcx_getTotalSupply(coinType)
+func (s *Server) getTotalSupply(p params) (interface{}, *rpcError) {
+	coinType, err := p.coinType(0)
+	if err != nil {
+		return nil, err
+	}
+	supply, ok := s.ledger.Supply[coinType]
+	if !ok {
+		return nil, newRPCError(codeServerError, "未知的币类型: %s", coinType)
+	}
+	return benfen.Supply{Value: benfen.Uint64(supply)}, nil
+}
+
+// findByType 查找指定类型的对象，多个时返回 ID 最小的
+func (s *Server) findByType(objectType string) *object {
+	var found *object
+	for _, obj := range s.ledger.Objects {
+		if obj.Type == objectType && (found == nil || obj.ID < found.ID) {
+			found = obj
+		}
+	}
+	return found
+}
+
+// getObject bfc_getObject(objectID, options)，不存在的对象返回 notExists 错误
+func (s *Server) getObject(p params) (interface{}, *rpcError) {
+	id, err := p.address(0)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := s.ledger.Objects[id]
+	if !ok {
+		notExists, _ := json.Marshal(map[string]string{"code": "notExists", "object_id": id})
+		return benfen.ObjectResponse{Error: notExists}, nil
+	}
+
+	fields := map[string]interface{}{"id": map[string]string{"id": obj.ID}}
+	if obj.coinType() != "" {
+		fields["balance"] = strconv.FormatUint(obj.Balance, 10)
+	}
+	if obj.Meta != nil {
+		fields["decimals"] = obj.Meta.Decimals
+		fields["name"] = obj.Meta.Name
+		fields["symbol"] = obj.Meta.Symbol
+		fields["description"] = obj.Meta.Description
+		fields["icon_url"] = obj.Meta.IconURL
+	}
+	content, _ := json.Marshal(map[string]interface{}{
+		"dataType": "moveObject",
+		"type":     obj.Type,
+		"fields":   fields,
+	})
+	ref := obj.ref()
+	return benfen.ObjectResponse{Data: &benfen.ObjectData{
+		ObjectID: obj.ID,
+		Version:  ref.Version,
+		Digest:   ref.Digest,
+		Type:     obj.Type,
+		Owner:    ownerJSON(obj.Owner),
+		Content:  content,
+	}}, nil
+}
+
+// getTransaction bfc_getTransactionBlock(digest, options)
+func (s *Server) getTransaction(p params) (interface{}, *rpcError) {
+	var digest string
+	if err := p.decode(0, &digest); err != nil {
+		return nil, err
+	}
+	resp, ok := s.ledger.Txs[digest]
+	if !ok {
+		return nil, newRPCError(codeServerError, "Could not find the referenced transaction [TransactionDigest(%s)]", digest)
+	}
+	return resp, nil
+}
+
+// buildPublish unsafe_publish(sender, modules, dependencies, gas, gasBudget)
+func (s *Server) buildPublish(p params) (interface{}, *rpcError) {
+	var modules []string
+	if err := p.decode(1, &modules); err != nil {
+		return nil, err
+	}
+	if len(modules) == 0 {
+		return nil, newRPCError(codeInvalidParams, "compiled_modules 不能为空")
+	}
+	code := make([][]byte, len(modules))
+	for i, module := range modules {
+		raw, err := base64.StdEncoding.DecodeString(module)
+		if err != nil {
+			return nil, newRPCError(codeInvalidParams, "第 %d 个模块不是有效的 base64: %v", i+1, err)
+		}
+		code[i] = raw
+	}
+	return s.build(p, 0, 3, 4, txData{Kind: txPublish, Modules: len(modules), code: code})
+}
+
+// buildMoveCall unsafe_moveCall(sender, package, module, function, typeArgs, args, gas, gasBudget)
+func (s *Server) buildMoveCall(p params) (interface{}, *rpcError) {
+	var call moveCall
+	for i, field := range []interface{}{&call.Package, &call.Module, &call.Function, &call.TypeArgs, &call.Arguments} {
+		if err := p.decode(i+1, field); err != nil {
+			return nil, err
+		}
+	}
+	return s.build(p, 0, 6, 7, txData{Kind: txMoveCall, Calls: []moveCall{call}})
+}
+
+// buildBatch unsafe_batchTransaction(sender, params, gas, gasBudget)，只支持 Move 调用
+func (s *Server) buildBatch(p params) (interface{}, *rpcError) {
+	var items []struct {
+		MoveCall *moveCall `json:"moveCallRequestParams"`
+	}
+	if err := p.decode(1, &items); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, newRPCError(codeInvalidParams, "批量交易不能为空")
+	}
+	calls := make([]moveCall, len(items))
+	for i, item := range items {
+		if item.MoveCall == nil {
+			return nil, newRPCError(codeInvalidParams, "模拟节点只支持 moveCallRequestParams")
+		}
+		calls[i] = *item.MoveCall
+	}
+	return s.build(p, 0, 2, 3, txData{Kind: txMoveCall, Calls: calls})
+}
+
+// buildTransfer unsafe_transferObject(sender, objectID, gas, gasBudget, recipient)
+func (s *Server) buildTransfer(p params) (interface{}, *rpcError) {
+	objectID, err := p.address(1)
+	if err != nil {
+		return nil, err
+	}
+	recipient, err := p.address(4)
+	if err != nil {
+		return nil, err
+	}
+	return s.build(p, 0, 2, 3, txData{Kind: txTransfer, Object: objectID, Recipient: recipient})
+}
+
+// build 校验发送者和 gas 并生成交易字节，gas 为空时选择余额足够的第一个 BFC 币
+func (s *Server) build(p params, senderIndex, gasIndex, budgetIndex int, tx txData) (interface{}, *rpcError) {
+	sender, err := p.address(senderIndex)
+	if err != nil {
+		return nil, err
+	}
+	var budget benfen.Uint64
+	if err := p.decode(budgetIndex, &budget); err != nil {
+		return nil, err
+	}
+	if budget == 0 {
+		return nil, newRPCError(codeInvalidParams, "gas_budget 必须大于 0")
+	}
+	var gasID string
+	if err := p.decode(gasIndex, &gasID); err != nil {
+		return nil, err
+	}
+
+	tx.Sender = sender
+	tx.GasBudget = uint64(budget)
+	gas, rpcErr := s.gasCoin(&tx, gasID)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	tx.Gas = gas.ID
+
+	s.builds++
+	tx.Seq = s.builds
+	data, _ := json.Marshal(tx)
+	// 与真实交易一样以 BCS vector<vector<u8>> 携带模块字节码
+	if len(tx.code) > 0 {
+		data = appendULEB128(data, uint64(len(tx.code)))
+		for _, code := range tx.code {
+			data = append(appendULEB128(data, uint64(len(code))), code...)
+		}
+	}
+	return benfen.TransactionBlockBytes{
+		TxBytes:      base64.StdEncoding.EncodeToString(data),
+		Gas:          []benfen.ObjectRef{gas.ref()},
+		InputObjects: []json.RawMessage{},
+	}, nil
+}
+
+// gasCoin 校验或选择 gas 币：必须是发送者持有的 BFC 币且余额不低于 gas 预算，
+// 自动选择时跳过交易参数中的对象
+func (s *Server) gasCoin(tx *txData, gasID string) (*object, *rpcError) {
+	if gasID == "" {
+		for _, coin := range s.ledger.coins(tx.Sender, benfen.BFCCoinType) {
+			if coin.Balance >= tx.GasBudget && !tx.uses(coin.ID) {
+				return coin, nil
+			}
+		}
+		return nil, newRPCError(codeServerError, "发送者 %s 没有余额不低于 %d 的 BFC 币", tx.Sender, tx.GasBudget)
+	}
+
+	id, err := normalizeID(gasID)
+	if err != nil {
+		return nil, newRPCError(codeInvalidParams, "无效的 gas 币 ID: %v", err)
+	}
+	coin, ok := s.ledger.Objects[id]
+	if !ok {
+		return nil, newRPCError(codeServerError, "gas 币不存在: %s", id)
+	}
+	if coin.Owner != tx.Sender || coin.coinType() != benfen.BFCCoinType {
+		return nil, newRPCError(codeServerError, "gas 币 %s 不是发送者持有的 BFC 币", id)
+	}
+	if coin.Balance < tx.GasBudget {
+		return nil, newRPCError(codeServerError, "gas 币余额 %d 低于 gas 预算 %d", coin.Balance, tx.GasBudget)
+	}
+	return coin, nil
+}
+
+// executeSigned bfc_executeTransactionBlock(txBytes, signatures, options, requestType)。
+// 模拟节点不验证签名，只要求至少有一个签名；同一交易重复提交返回第一次的结果
+func (s *Server) executeSigned(p params, executionError string) (interface{}, *rpcError) {
+	var txBytes, requestType string
+	var signatures []string
+	if err := p.decode(0, &txBytes); err != nil {
+		return nil, err
+	}
+	if err := p.decode(1, &signatures); err != nil {
+		return nil, err
+	}
+	if err := p.decode(3, &requestType); err != nil {
+		return nil, err
+	}
+	if len(signatures) == 0 {
+		return nil, newRPCError(codeInvalidParams, "缺少交易签名")
+	}
+
+	resp, rpcErr := s.execute(txBytes, executionError, true)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	if requestType == benfen.WaitForLocalExecution {
+		confirmed := true
+		copied := *resp
+		copied.ConfirmedLocalExecution = &confirmed
+		resp = &copied
+	}
+	return resp, nil
+}
+
+// execute 执行交易。commit 为 false 时为预执行，不修改状态；
+// 执行失败时只扣除 gas，其他变更全部回滚
+func (s *Server) execute(txBytes, executionError string, commit bool) (*benfen.TransactionBlockResponse, *rpcError) {
+	raw, err := base64.StdEncoding.DecodeString(txBytes)
+	if err != nil {
+		return nil, newRPCError(codeInvalidParams, "无效的交易字节: %v", err)
+	}
+	// JSON 之后可能跟着模块字节码，只解析开头的 JSON
+	var tx txData
+	if err := json.NewDecoder(bytes.NewReader(raw)).Decode(&tx); err != nil || tx.Kind == "" {
+		return nil, newRPCError(codeInvalidParams, "交易字节不是模拟节点生成的交易")
+	}
+	digest, err := benfen.TransactionDigest(txBytes)
+	if err != nil {
+		return nil, newRPCError(codeInvalidParams, "%v", err)
+	}
+	if commit {
+		if resp, ok := s.ledger.Txs[digest]; ok {
+			return resp, nil
+		}
+	}
+	if _, rpcErr := s.gasCoin(&tx, tx.Gas); rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	work := s.ledger.clone()
+	exe := &execution{l: work, tx: &tx}
+	if executionError != "" {
+		err = abort("%s", executionError)
+	} else {
+		err = exe.run(s.opts.Publish)
+	}
+
+	gasUsed := benfen.GasCostSummary{ComputationCost: computationCost}
+	if err == nil {
+		gasUsed.StorageCost = benfen.Uint64(storageCostPerItem * (exe.created + 1))
+		gasUsed.StorageRebate = storageRebate
+		if uint64(gasUsed.ComputationCost+gasUsed.StorageCost) > tx.GasBudget {
+			err = abort("InsufficientGas")
+		}
+	}
+	status := benfen.ExecutionStatus{Status: "success"}
+	if err != nil {
+		var failure *execError
+		if !errors.As(err, &failure) {
+			return nil, newRPCError(codeServerError, "%v", err)
+		}
+		status = benfen.ExecutionStatus{Status: "failure", Error: failure.message}
+		gasUsed = benfen.GasCostSummary{ComputationCost: benfen.Uint64(min(computationCost, tx.GasBudget))}
+		work = s.ledger.clone()
+		exe = &execution{l: work, tx: &tx}
+	}
+
+	// 扣除 gas：计算费 + 存储费 - 存储返还
+	charge := uint64(gasUsed.ComputationCost + gasUsed.StorageCost - gasUsed.StorageRebate)
+	gas := work.Objects[tx.Gas]
+	gas.Balance -= charge
+	work.Supply[benfen.BFCCoinType] -= charge
+	exe.recordMutated(gas)
+
+	resp := &benfen.TransactionBlockResponse{
+		Digest:      digest,
+		Transaction: &benfen.TransactionBlock{Data: benfen.TransactionBlockData{Sender: tx.Sender}},
+		Effects: &benfen.TransactionEffects{
+			Status:            status,
+			GasUsed:           gasUsed,
+			TransactionDigest: digest,
+		},
+		ObjectChanges: exe.changes,
+	}
+	if commit {
+		work.Checkpoint++
+		work.Txs[digest] = resp
+		s.ledger = work
+	}
+	return resp, nil
+}
+
+// appendULEB128 追加 ULEB128 编码的整数
+func appendULEB128(dst []byte, n uint64) []byte {
+	for n >= 0x80 {
+		dst = append(dst, byte(n)|0x80)
+		n >>= 7
+	}
+	return append(dst, byte(n))
+}
@@ -0,0 +1,298 @@
+// Package mockrpc 内存中的 Benfen JSON-RPC 模拟节点，用于本地开发和联调。
+// 状态完全确定：同样的请求序列得到同样的对象 ID、交易摘要和余额。
+package mockrpc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"obc_coin_api/benfen"
+)
+
+// 默认配置
+const (
+	DefaultChainID = "mock"
+	// 请求体大小上限，发布交易包含完整字节码
+	maxRequestBytes = 16 << 20
+)
+
+// PublishTemplate 发布交易创建的代币。模拟节点不解析字节码，所有发布的包都使用该模板
+type PublishTemplate struct {
+	Module  string `json:"module"`
+	Witness string `json:"witness"`
+	// Decimals 为 nil 时使用默认精度 9，0 是有效的精度
+	Decimals *uint8 `json:"decimals,omitempty"`
+	Name     string `json:"name"`
+	Symbol   string `json:"symbol"`
+}
+
+// Options 模拟节点配置
+type Options struct {
+	// ChainID bfc_getChainIdentifier 返回的链 ID
+	ChainID string
+	// Publish 发布交易创建的代币，字段为空时使用 fast_coin 模板的默认值
+	Publish PublishTemplate
+	// Accounts 启动和重置时为地址发放的 BFC（最小单位）
+	Accounts map[string]uint64
+	// Failures 启动和重置时加载的故障脚本
+	Failures []Failure
+}
+
+// Server 模拟节点，实现 http.Handler
+type Server struct {
+	opts     Options
+	mux      *http.ServeMux
+	failures failureScript
+
+	mu     sync.Mutex
+	ledger *ledger
+	builds uint64
+}
+
+// New 创建模拟节点并按配置初始化账户
+func New(opts Options) (*Server, error) {
+	if opts.ChainID == "" {
+		opts.ChainID = DefaultChainID
+	}
+	if opts.Publish.Module == "" {
+		opts.Publish.Module = "fast_coin"
+	}
+	if opts.Publish.Witness == "" {
+		opts.Publish.Witness = "FAST_COIN"
+	}
+	if opts.Publish.Decimals == nil {
+		decimals := uint8(9)
+		opts.Publish.Decimals = &decimals
+	}
+	if opts.Publish.Name == "" {
+		opts.Publish.Name = "Fast Coin"
+	}
+	if opts.Publish.Symbol == "" {
+		opts.Publish.Symbol = "FAST"
+	}
+	for owner := range opts.Accounts {
+		if _, err := normalizeID(owner); err != nil {
+			return nil, fmt.Errorf("无效的账户地址 %s: %v", owner, err)
+		}
+	}
+
+	s := &Server{opts: opts}
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/", s.handleRPC)
+	s.mux.HandleFunc("/mock/state", s.handleState)
+	s.mux.HandleFunc("/mock/reset", s.handleReset)
+	s.mux.HandleFunc("/mock/faucet", s.handleFaucet)
+	s.mux.HandleFunc("/mock/failures", s.handleFailures)
+	s.Reset()
+	return s, nil
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Reset 清空链上状态，重新发放初始账户的 BFC 并加载初始故障脚本
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.ledger = newLedger()
+	s.builds = 0
+	for _, owner := range sortedKeys(s.opts.Accounts) {
+		id, _ := normalizeID(owner)
+		s.ledger.mint(id, benfen.BFCCoinType, s.opts.Accounts[owner])
+	}
+	s.mu.Unlock()
+
+	s.failures.clear()
+	s.failures.add(s.opts.Failures...)
+}
+
+// Fund 为地址创建一个新的 BFC 币，返回币对象 ID
+func (s *Server) Fund(owner string, amount uint64) (string, error) {
+	id, err := normalizeID(owner)
+	if err != nil {
+		return "", err
+	}
+	if amount == 0 {
+		return "", errors.New("数量必须大于 0")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ledger.mint(id, benfen.BFCCoinType, amount).ID, nil
+}
+
+// AddFailures 追加故障
+func (s *Server) AddFailures(failures ...Failure) {
+	s.failures.add(failures...)
+}
+
+// ClearFailures 清除全部故障
+func (s *Server) ClearFailures() {
+	s.failures.clear()
+}
+
+// rpcRequest 收到的 JSON-RPC 请求，参数按位置延迟解析
+type rpcRequest struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+// rpcError 返回给客户端的 JSON-RPC 错误
+type rpcError = benfen.RPCError
+
+// 常用错误码
+const (
+	codeInvalidParams  = -32602
+	codeMethodNotFound = -32601
+	codeServerError    = -32000
+)
+
+func newRPCError(code int, format string, args ...interface{}) *rpcError {
+	return &rpcError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// handleRPC 处理 JSON-RPC 请求，先匹配故障脚本再分发到具体方法
+func (s *Server) handleRPC(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req rpcRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeRPC(w, nil, nil, newRPCError(-32700, "无效的 JSON: %v", err))
+		return
+	}
+
+	if failure, ok := s.failures.match(req.Method); ok {
+		log.Printf("模拟节点触发故障 %s: %+v", req.Method, failure)
+		time.Sleep(failure.Delay())
+		switch {
+		case failure.HTTPStatus != 0:
+			http.Error(w, http.StatusText(failure.HTTPStatus), failure.HTTPStatus)
+			return
+		case failure.ExecutionError != "":
+			if req.Method == benfen.MethodDryRun || req.Method == benfen.MethodExecute {
+				result, rpcErr := s.dispatch(req, failure.ExecutionError)
+				writeRPC(w, req.ID, result, rpcErr)
+				return
+			}
+		case failure.Message != "" || failure.Code != 0:
+			code := failure.Code
+			if code == 0 {
+				code = codeServerError
+			}
+			writeRPC(w, req.ID, nil, &rpcError{Code: code, Message: failure.Message})
+			return
+		}
+	}
+
+	result, rpcErr := s.dispatch(req, "")
+	writeRPC(w, req.ID, result, rpcErr)
+}
+
+// writeRPC 写出 JSON-RPC 响应
+func writeRPC(w http.ResponseWriter, id json.RawMessage, result interface{}, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": id}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("模拟节点写出响应失败: %v", err)
+	}
+}
+
+// handleState 导出当前链上状态，便于调试
+func (s *Server) handleState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "只支持 GET", http.StatusMethodNotAllowed)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.ledger)
+}
+
+// handleReset 重置链上状态和故障脚本
+func (s *Server) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	s.Reset()
+	writeJSON(w, http.StatusOK, map[string]bool{"reset": true})
+}
+
+// handleFaucet 为地址发放 BFC: {"address": "...", "amount": 1000000000}
+func (s *Server) handleFaucet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "只支持 POST", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Address string        `json:"address"`
+		Amount  benfen.Uint64 `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "无效的请求格式", http.StatusBadRequest)
+		return
+	}
+	id, err := s.Fund(req.Address, uint64(req.Amount))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"coin_object_id": id})
+}
+
+// handleFailures 查看(GET)、追加(POST，Failure 数组)或清除(DELETE)故障脚本
+func (s *Server) handleFailures(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var failures []Failure
+		if err := json.NewDecoder(r.Body).Decode(&failures); err != nil {
+			http.Error(w, "请求体必须是故障数组", http.StatusBadRequest)
+			return
+		}
+		s.AddFailures(failures...)
+	case http.MethodDelete:
+		s.ClearFailures()
+	default:
+		http.Error(w, "只支持 GET、POST 和 DELETE", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.failures.list())
+}
+
+// sortedKeys 按地址排序，保证初始账户的币对象 ID 稳定
+func sortedKeys(accounts map[string]uint64) []string {
+	keys := make([]string, 0, len(accounts))
+	for owner := range accounts {
+		keys = append(keys, owner)
+	}
+	sort.Strings(keys)
+	return keys
+}
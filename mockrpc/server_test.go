@@ -0,0 +1,131 @@
+package mockrpc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"obc_coin_api/benfen"
+)
+
+const testSender = "0x00000000000000000000000000000000000000000000000000000000000000aa"
+
+// newTestClient 启动模拟节点并返回指向它的 RPC 客户端
+func newTestClient(t *testing.T, opts Options) *benfen.Client {
+	t.Helper()
+	if opts.Accounts == nil {
+		opts.Accounts = map[string]uint64{testSender: 100000000000}
+	}
+	server, err := New(opts)
+	if err != nil {
+		t.Fatalf("创建模拟节点失败: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	return benfen.NewClient(benfen.Options{URLs: []string{ts.URL}, Timeout: 5 * time.Second})
+}
+
+// publish 构建、执行发布交易并返回执行结果
+func publish(t *testing.T, client *benfen.Client) *benfen.TransactionBlockResponse {
+	t.Helper()
+	ctx := context.Background()
+	module := base64.StdEncoding.EncodeToString([]byte("fast_coin bytecode"))
+
+	var built benfen.TransactionBlockBytes
+	if err := client.Call(ctx, benfen.MethodUnsafePublish, &built,
+		testSender, []string{module}, []string{"0x1", "0x2"}, "", "10000000"); err != nil {
+		t.Fatalf("构建发布交易失败: %v", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(built.TxBytes)
+	if err != nil {
+		t.Fatalf("交易字节不是 base64: %v", err)
+	}
+	if !strings.Contains(string(raw), "fast_coin bytecode") {
+		t.Fatalf("交易字节中没有模块字节码")
+	}
+
+	signature := base64.StdEncoding.EncodeToString(make([]byte, 97))
+	options := benfen.TransactionBlockResponseOptions{ShowEffects: true, ShowObjectChanges: true}
+	var executed benfen.TransactionBlockResponse
+	if err := client.Call(ctx, benfen.MethodExecute, &executed,
+		built.TxBytes, []string{signature}, options, benfen.WaitForLocalExecution); err != nil {
+		t.Fatalf("执行发布交易失败: %v", err)
+	}
+	return &executed
+}
+
+func TestPublishExecuteGetTransaction(t *testing.T) {
+	client := newTestClient(t, Options{})
+	executed := publish(t, client)
+
+	if executed.Effects == nil || executed.Effects.Status.Status != "success" {
+		t.Fatalf("发布交易未成功: %+v", executed.Effects)
+	}
+	var packageID, coinType string
+	for _, change := range executed.ObjectChanges {
+		switch {
+		case change.Type == "published":
+			packageID = change.PackageID
+		case change.Type == "created" && strings.HasPrefix(change.ObjectType, "0x2::coin::TreasuryCap<"):
+			coinType = strings.TrimSuffix(strings.TrimPrefix(change.ObjectType, "0x2::coin::TreasuryCap<"), ">")
+		}
+	}
+	if packageID == "" {
+		t.Fatalf("对象变更中没有发布的包: %+v", executed.ObjectChanges)
+	}
+	if want := packageID + "::fast_coin::FAST_COIN"; coinType != want {
+		t.Fatalf("代币类型为 %s，应为 %s", coinType, want)
+	}
+
+	var fetched benfen.TransactionBlockResponse
+	options := benfen.TransactionBlockResponseOptions{ShowEffects: true, ShowObjectChanges: true}
+	if err := client.Call(context.Background(), benfen.MethodGetTransaction, &fetched, executed.Digest, options); err != nil {
+		t.Fatalf("查询交易失败: %v", err)
+	}
+	if fetched.Digest != executed.Digest {
+		t.Fatalf("查询到的交易摘要为 %s，应为 %s", fetched.Digest, executed.Digest)
+	}
+	if fetched.Effects == nil || fetched.Effects.Status.Status != "success" {
+		t.Fatalf("查询到的交易未成功: %+v", fetched.Effects)
+	}
+	if fetched.Transaction == nil || fetched.Transaction.Data.Sender != testSender {
+		t.Fatalf("查询到的交易发送者应为 %s: %+v", testSender, fetched.Transaction)
+	}
+	if len(fetched.ObjectChanges) != len(executed.ObjectChanges) {
+		t.Fatalf("查询到 %d 个对象变更，执行时为 %d 个", len(fetched.ObjectChanges), len(executed.ObjectChanges))
+	}
+}
+
+func TestPublishTemplateDecimals(t *testing.T) {
+	zero := uint8(0)
+	for _, tc := range []struct {
+		name     string
+		decimals *uint8
+		want     uint8
+	}{
+		{"默认精度", nil, 9},
+		{"零精度", &zero, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, Options{Publish: PublishTemplate{Decimals: tc.decimals}})
+			executed := publish(t, client)
+
+			var coinType string
+			for _, change := range executed.ObjectChanges {
+				if strings.HasPrefix(change.ObjectType, "0x2::coin::CoinMetadata<") {
+					coinType = strings.TrimSuffix(strings.TrimPrefix(change.ObjectType, "0x2::coin::CoinMetadata<"), ">")
+				}
+			}
+			var metadata benfen.CoinMetadata
+			if err := client.Call(context.Background(), benfen.MethodGetCoinMetadata, &metadata, coinType); err != nil {
+				t.Fatalf("查询元数据失败: %v", err)
+			}
+			if metadata.Decimals != tc.want {
+				t.Fatalf("精度为 %d，应为 %d", metadata.Decimals, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,589 @@
+package mockrpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"obc_coin_api/address"
+	"obc_coin_api/benfen"
+)
+
+// 固定的 gas 消耗，保证同样的交易得到同样的结果
+const (
+	computationCost    = 1000000
+	storageCostPerItem = 1000000
+	storageRebate      = 500000
+)
+
+// 对象所有者
+const (
+	ownerImmutable = "Immutable"
+	ownerShared    = "Shared"
+)
+
+// object 内存中的链上对象
+type object struct {
+	ID      string               `json:"id"`
+	Version uint64               `json:"version"`
+	Type    string               `json:"type"`
+	Owner   string               `json:"owner"`
+	Balance uint64               `json:"balance,omitempty"`
+	Meta    *benfen.CoinMetadata `json:"metadata,omitempty"`
+	Modules int                  `json:"modules,omitempty"`
+}
+
+// digest 由对象 ID 和版本生成的确定性摘要
+func (o *object) digest() string {
+	return fmt.Sprintf("%s-v%d", strings.TrimPrefix(o.ID, "0x")[56:], o.Version)
+}
+
+func (o *object) ref() benfen.ObjectRef {
+	return benfen.ObjectRef{ObjectID: o.ID, Version: benfen.Uint64(o.Version), Digest: o.digest()}
+}
+
+// coinType 币对象的币类型，不是币时返回空
+func (o *object) coinType() string {
+	if inner, ok := typeArgument(o.Type, "0x2::coin::Coin<"); ok {
+		return inner
+	}
+	return ""
+}
+
+// typeArgument 提取 prefix<T> 中的 T
+func typeArgument(objectType, prefix string) (string, bool) {
+	if !strings.HasPrefix(objectType, prefix) || !strings.HasSuffix(objectType, ">") {
+		return "", false
+	}
+	return objectType[len(prefix) : len(objectType)-1], true
+}
+
+// ownerJSON 按节点格式编码所有者
+func ownerJSON(owner string) json.RawMessage {
+	var value interface{}
+	switch owner {
+	case ownerImmutable:
+		value = ownerImmutable
+	case ownerShared:
+		value = map[string]interface{}{"Shared": map[string]string{"initial_shared_version": "1"}}
+	default:
+		value = map[string]string{"AddressOwner": owner}
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
+
+// ledger 全部链上状态
+type ledger struct {
+	Objects    map[string]*object                          `json:"objects"`
+	Supply     map[string]uint64                           `json:"supply"`
+	Txs        map[string]*benfen.TransactionBlockResponse `json:"transactions"`
+	Checkpoint uint64                                      `json:"checkpoint"`
+	Counter    uint64                                      `json:"counter"`
+}
+
+func newLedger() *ledger {
+	return &ledger{
+		Objects: make(map[string]*object),
+		Supply:  make(map[string]uint64),
+		Txs:     make(map[string]*benfen.TransactionBlockResponse),
+	}
+}
+
+// clone 深拷贝状态，预执行在副本上进行
+func (l *ledger) clone() *ledger {
+	c := newLedger()
+	for id, obj := range l.Objects {
+		copied := *obj
+		if obj.Meta != nil {
+			meta := *obj.Meta
+			copied.Meta = &meta
+		}
+		c.Objects[id] = &copied
+	}
+	for coinType, supply := range l.Supply {
+		c.Supply[coinType] = supply
+	}
+	c.Txs = l.Txs
+	c.Checkpoint = l.Checkpoint
+	c.Counter = l.Counter
+	return c
+}
+
+// nextID 生成确定性的对象 ID
+func (l *ledger) nextID() string {
+	l.Counter++
+	return fmt.Sprintf("0x%064x", 0x1000+l.Counter)
+}
+
+// create 创建对象
+func (l *ledger) create(objectType, owner string) *object {
+	obj := &object{ID: l.nextID(), Version: 1, Type: objectType, Owner: owner}
+	l.Objects[obj.ID] = obj
+	return obj
+}
+
+// mint 给地址增加 BFC 或其他币
+func (l *ledger) mint(owner, coinType string, amount uint64) *object {
+	coin := l.create("0x2::coin::Coin<"+coinType+">", owner)
+	coin.Balance = amount
+	l.Supply[coinType] += amount
+	return coin
+}
+
+// coins 地址持有的某类币，按对象 ID 排序
+func (l *ledger) coins(owner, coinType string) []*object {
+	var coins []*object
+	for _, obj := range l.Objects {
+		if obj.Owner == owner && obj.coinType() == coinType {
+			coins = append(coins, obj)
+		}
+	}
+	sort.Slice(coins, func(i, j int) bool { return coins[i].ID < coins[j].ID })
+	return coins
+}
+
+// normalizeID 将地址或对象 ID 统一为完整的 0x 格式
+func normalizeID(text string) (string, error) {
+	addr, err := address.Parse(text)
+	if err != nil {
+		return "", err
+	}
+	return addr.Hex(), nil
+}
+
+// normalizeCoinType 统一代币类型中的地址，0x2::bfc::BFC 保持节点习惯的短格式
+func normalizeCoinType(coinType string) string {
+	parts := strings.SplitN(coinType, "::", 2)
+	if len(parts) != 2 {
+		return coinType
+	}
+	id, err := normalizeID(parts[0])
+	if err != nil {
+		return coinType
+	}
+	if id == "0x0000000000000000000000000000000000000000000000000000000000000002" {
+		return "0x2::" + parts[1]
+	}
+	return id + "::" + parts[1]
+}
+
+// txData 未签名交易的内容，以 base64(JSON) 作为交易字节返回，执行时原样解析；
+// 发布交易在 JSON 之后附加模块字节码
+type txData struct {
+	Seq       uint64     `json:"seq"`
+	Kind      string     `json:"kind"`
+	Sender    string     `json:"sender"`
+	Gas       string     `json:"gas"`
+	GasBudget uint64     `json:"gas_budget"`
+	Modules   int        `json:"modules,omitempty"`
+	Calls     []moveCall `json:"calls,omitempty"`
+	Object    string     `json:"object,omitempty"`
+	Recipient string     `json:"recipient,omitempty"`
+	code      [][]byte
+}
+
+// uses 交易是否将对象作为参数使用，自动选择 gas 时跳过这些对象
+func (tx *txData) uses(id string) bool {
+	if tx.Object == id {
+		return true
+	}
+	for _, call := range tx.Calls {
+		for _, arg := range call.Arguments {
+			if text, ok := arg.(string); ok {
+				if argID, err := normalizeID(text); err == nil && argID == id {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// 交易类型
+const (
+	txPublish  = "publish"
+	txMoveCall = "move_call"
+	txTransfer = "transfer_object"
+)
+
+// moveCall 一次 Move 调用
+type moveCall struct {
+	Package   string        `json:"packageObjectId"`
+	Module    string        `json:"module"`
+	Function  string        `json:"function"`
+	TypeArgs  []string      `json:"typeArguments"`
+	Arguments []interface{} `json:"arguments"`
+}
+
+// execError 交易执行失败，对应失败状态的交易效果
+type execError struct {
+	message string
+}
+
+func (e *execError) Error() string { return e.message }
+
+func abort(format string, args ...interface{}) error {
+	return &execError{message: fmt.Sprintf(format, args...)}
+}
+
+// execution 一次交易执行过程中的对象变更
+type execution struct {
+	l       *ledger
+	tx      *txData
+	changes []benfen.ObjectChange
+	created int
+}
+
+// owned 获取发送者持有的对象
+func (e *execution) owned(id interface{}) (*object, error) {
+	text, ok := id.(string)
+	if !ok {
+		return nil, abort("对象参数必须是字符串: %v", id)
+	}
+	objectID, err := normalizeID(text)
+	if err != nil {
+		return nil, abort("无效的对象 ID %s: %v", text, err)
+	}
+	obj, ok := e.l.Objects[objectID]
+	if !ok {
+		return nil, abort("对象不存在: %s", objectID)
+	}
+	if obj.Owner != e.tx.Sender {
+		return nil, abort("对象 %s 不属于发送者 %s", objectID, e.tx.Sender)
+	}
+	return obj, nil
+}
+
+// recordCreated 记录新建对象
+func (e *execution) recordCreated(obj *object) {
+	e.created++
+	e.changes = append(e.changes, benfen.ObjectChange{
+		Type:       "created",
+		Sender:     e.tx.Sender,
+		Owner:      ownerJSON(obj.Owner),
+		ObjectType: obj.Type,
+		ObjectID:   obj.ID,
+		Version:    benfen.Uint64(obj.Version),
+		Digest:     obj.digest(),
+	})
+}
+
+// recordMutated 记录对象变更，同一交易内多次修改同一对象只记录一次
+func (e *execution) recordMutated(obj *object) {
+	for i := range e.changes {
+		if e.changes[i].ObjectID == obj.ID {
+			e.changes[i].Owner = ownerJSON(obj.Owner)
+			return
+		}
+	}
+	obj.Version++
+	e.changes = append(e.changes, benfen.ObjectChange{
+		Type:       "mutated",
+		Sender:     e.tx.Sender,
+		Owner:      ownerJSON(obj.Owner),
+		ObjectType: obj.Type,
+		ObjectID:   obj.ID,
+		Version:    benfen.Uint64(obj.Version),
+		Digest:     obj.digest(),
+	})
+}
+
+// recordDeleted 记录对象删除，同一交易内先修改后删除的对象只记录删除
+func (e *execution) recordDeleted(obj *object) {
+	delete(e.l.Objects, obj.ID)
+	for i := range e.changes {
+		if e.changes[i].ObjectID == obj.ID {
+			if e.changes[i].Type == "created" {
+				e.changes = append(e.changes[:i], e.changes[i+1:]...)
+				return
+			}
+			e.changes = append(e.changes[:i], e.changes[i+1:]...)
+			obj.Version--
+			break
+		}
+	}
+	e.changes = append(e.changes, benfen.ObjectChange{
+		Type:       "deleted",
+		Sender:     e.tx.Sender,
+		ObjectType: obj.Type,
+		ObjectID:   obj.ID,
+		Version:    benfen.Uint64(obj.Version + 1),
+	})
+}
+
+// run 执行交易内容，失败时返回 *execError
+func (e *execution) run(publish PublishTemplate) error {
+	switch e.tx.Kind {
+	case txPublish:
+		return e.publish(publish)
+	case txMoveCall:
+		for _, call := range e.tx.Calls {
+			if err := e.call(call); err != nil {
+				return err
+			}
+		}
+		return nil
+	case txTransfer:
+		obj, err := e.owned(e.tx.Object)
+		if err != nil {
+			return err
+		}
+		if obj.ID == e.tx.Gas {
+			return abort("gas 币 %s 不能被转移", obj.ID)
+		}
+		obj.Owner = e.tx.Recipient
+		e.recordMutated(obj)
+		return nil
+	default:
+		return abort("未知交易类型: %s", e.tx.Kind)
+	}
+}
+
+// publish 创建包、TreasuryCap、CoinMetadata 和 UpgradeCap
+func (e *execution) publish(template PublishTemplate) error {
+	if e.tx.Modules == 0 {
+		return abort("发布交易没有模块")
+	}
+	packageID := e.l.nextID()
+	coinType := fmt.Sprintf("%s::%s::%s", packageID, template.Module, template.Witness)
+	e.changes = append(e.changes, benfen.ObjectChange{
+		Type:      "published",
+		PackageID: packageID,
+		Modules:   []string{template.Module},
+		Version:   1,
+		Digest:    "package",
+	})
+	e.l.Objects[packageID] = &object{ID: packageID, Version: 1, Type: "package", Owner: ownerImmutable, Modules: e.tx.Modules}
+
+	treasury := e.l.create("0x2::coin::TreasuryCap<"+coinType+">", e.tx.Sender)
+	e.recordCreated(treasury)
+	metadata := e.l.create("0x2::coin::CoinMetadata<"+coinType+">", e.tx.Sender)
+	metadata.Meta = &benfen.CoinMetadata{
+		ID:       metadata.ID,
+		Decimals: *template.Decimals,
+		Name:     template.Name,
+		Symbol:   template.Symbol,
+	}
+	e.recordCreated(metadata)
+	upgrade := e.l.create("0x2::package::UpgradeCap", e.tx.Sender)
+	e.recordCreated(upgrade)
+	e.l.Supply[coinType] = 0
+	return nil
+}
+
+// call 执行一次 Move 调用，只模拟服务用到的函数，其他调用视为成功且不改变状态
+func (e *execution) call(call moveCall) error {
+	target := call.Module + "::" + call.Function
+	var coinType string
+	if len(call.TypeArgs) > 0 {
+		coinType = normalizeCoinType(call.TypeArgs[0])
+	}
+	args := call.Arguments
+
+	switch target {
+	case "coin::mint_and_transfer":
+		if len(args) != 3 {
+			return abort("%s 需要 3 个参数", target)
+		}
+		if err := e.checkTreasuryCap(args[0], coinType); err != nil {
+			return err
+		}
+		amount, err := uint64Arg(args[1])
+		if err != nil {
+			return err
+		}
+		recipient, err := addressArg(args[2])
+		if err != nil {
+			return err
+		}
+		e.recordCreated(e.l.mint(recipient, coinType, amount))
+
+	case "coin::burn":
+		if len(args) != 2 {
+			return abort("%s 需要 2 个参数", target)
+		}
+		if err := e.checkTreasuryCap(args[0], coinType); err != nil {
+			return err
+		}
+		coin, err := e.ownedCoin(args[1], coinType)
+		if err != nil {
+			return err
+		}
+		e.l.Supply[coinType] -= coin.Balance
+		e.recordDeleted(coin)
+
+	case "coin::join":
+		if len(args) != 2 {
+			return abort("%s 需要 2 个参数", target)
+		}
+		self, err := e.ownedCoin(args[0], coinType)
+		if err != nil {
+			return err
+		}
+		other, err := e.ownedCoin(args[1], coinType)
+		if err != nil {
+			return err
+		}
+		if self.ID == other.ID {
+			return abort("不能合并同一个币")
+		}
+		self.Balance += other.Balance
+		e.recordDeleted(other)
+		e.recordMutated(self)
+
+	case "pay::split_and_transfer":
+		if len(args) != 3 {
+			return abort("%s 需要 3 个参数", target)
+		}
+		coin, err := e.ownedCoin(args[0], coinType)
+		if err != nil {
+			return err
+		}
+		amount, err := uint64Arg(args[1])
+		if err != nil {
+			return err
+		}
+		recipient, err := addressArg(args[2])
+		if err != nil {
+			return err
+		}
+		if coin.Balance < amount {
+			return abort("余额不足: %d < %d", coin.Balance, amount)
+		}
+		coin.Balance -= amount
+		split := e.l.create(coin.Type, recipient)
+		split.Balance = amount
+		e.recordMutated(coin)
+		e.recordCreated(split)
+
+	case "transfer::public_freeze_object":
+		if len(args) != 1 {
+			return abort("%s 需要 1 个参数", target)
+		}
+		obj, err := e.owned(args[0])
+		if err != nil {
+			return err
+		}
+		obj.Owner = ownerImmutable
+		e.recordMutated(obj)
+
+	case "metadata::update_metadata":
+		if len(args) != 2 {
+			return abort("%s 需要 2 个参数", target)
+		}
+		obj, err := e.owned(args[0])
+		if err != nil {
+			return err
+		}
+		if obj.Meta == nil {
+			return abort("对象 %s 不是 CoinMetadata", obj.ID)
+		}
+		content, err := bytesArg(args[1])
+		if err != nil {
+			return err
+		}
+		var update struct {
+			Name        *string `json:"name"`
+			Symbol      *string `json:"symbol"`
+			Description *string `json:"description"`
+			IconURL     *string `json:"icon_url"`
+		}
+		if err := json.Unmarshal(content, &update); err != nil {
+			return abort("元数据不是有效的 JSON: %v", err)
+		}
+		if update.Name != nil {
+			obj.Meta.Name = *update.Name
+		}
+		if update.Symbol != nil {
+			obj.Meta.Symbol = *update.Symbol
+		}
+		if update.Description != nil {
+			obj.Meta.Description = *update.Description
+		}
+		if update.IconURL != nil {
+			obj.Meta.IconURL = *update.IconURL
+		}
+		e.recordMutated(obj)
+	}
+	return nil
+}
+
+// checkTreasuryCap 校验发送者持有该币类型的 TreasuryCap
+func (e *execution) checkTreasuryCap(arg interface{}, coinType string) error {
+	cap, err := e.owned(arg)
+	if err != nil {
+		return err
+	}
+	if cap.Type != "0x2::coin::TreasuryCap<"+coinType+">" {
+		return abort("对象 %s 不是 %s 的 TreasuryCap", cap.ID, coinType)
+	}
+	e.recordMutated(cap)
+	return nil
+}
+
+// ownedCoin 获取发送者持有的指定类型的币
+func (e *execution) ownedCoin(arg interface{}, coinType string) (*object, error) {
+	coin, err := e.owned(arg)
+	if err != nil {
+		return nil, err
+	}
+	if coin.coinType() != coinType {
+		return nil, abort("对象 %s 不是 %s 币", coin.ID, coinType)
+	}
+	if coin.ID == e.tx.Gas {
+		return nil, abort("gas 币 %s 不能作为参数", coin.ID)
+	}
+	return coin, nil
+}
+
+// uint64Arg 解析字符串或数字形式的 u64 参数
+func uint64Arg(arg interface{}) (uint64, error) {
+	switch value := arg.(type) {
+	case string:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return 0, abort("无效的 u64 参数: %s", value)
+		}
+		return n, nil
+	case float64:
+		return uint64(value), nil
+	default:
+		return 0, abort("无效的 u64 参数: %v", arg)
+	}
+}
+
+// addressArg 解析地址参数
+func addressArg(arg interface{}) (string, error) {
+	text, ok := arg.(string)
+	if !ok {
+		return "", abort("无效的地址参数: %v", arg)
+	}
+	addr, err := normalizeID(text)
+	if err != nil {
+		return "", abort("无效的地址参数 %s: %v", text, err)
+	}
+	return addr, nil
+}
+
+// bytesArg 解析 vector<u8> 参数，支持数字数组和字符串
+func bytesArg(arg interface{}) ([]byte, error) {
+	switch value := arg.(type) {
+	case string:
+		return []byte(value), nil
+	case []interface{}:
+		content := make([]byte, len(value))
+		for i, item := range value {
+			n, ok := item.(float64)
+			if !ok || n < 0 || n > 255 {
+				return nil, abort("无效的 vector<u8> 元素: %v", item)
+			}
+			content[i] = byte(n)
+		}
+		return content, nil
+	default:
+		return nil, abort("无效的 vector<u8> 参数: %v", arg)
+	}
+}
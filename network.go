@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"obc_coin_api/benfen"
+)
+
+// 启动时校验链 ID 的超时时间
+const chainIDCheckTimeout = 10 * time.Second
+
+// ErrUnknownNetwork 请求指定了未配置的网络
+var ErrUnknownNetwork = errors.New("未知网络")
+
+// Network 一个已配置的网络及其 RPC 客户端
+type Network struct {
+	Name   string
+	Config NetworkConfig
+	Client *benfen.Client
+}
+
+// 全部网络，按名称索引
+var networks map[string]*Network
+
+// initNetworks 为每个网络创建 RPC 客户端并启动节点健康检查
+func initNetworks() {
+	if err := checkLegacyRPCConfig(); err != nil {
+		log.Fatal(err)
+	}
+	networks = make(map[string]*Network)
+	for name, config := range GetNetworks() {
+		if len(config.URLs) == 0 {
+			log.Fatalf("网络 %s 未配置 RPC 节点", name)
+		}
+		transport, err := rpcTransport(name)
+		if err != nil {
+			log.Fatalf("网络 %s 初始化 RPC 录制/回放失败: %v", name, err)
+		}
+		network := &Network{
+			Name:   name,
+			Config: config,
+			Client: benfen.NewClient(benfen.Options{
+				URLs:                config.URLs,
+				Timeout:             time.Duration(GetBenfenRPCTimeout()) * time.Second,
+				RetryCount:          GetBenfenRPCRetryCount(),
+				HealthCheckInterval: time.Duration(GetBenfenRPCHealthCheckInterval()) * time.Second,
+				MaxCheckpointLag:    GetBenfenRPCMaxCheckpointLag(),
+				Transport:           transport,
+			}),
+		}
+		network.Client.StartHealthChecks(context.Background())
+		networks[name] = network
+		go network.checkChainID()
+	}
+
+	if _, ok := networks[GetDefaultNetwork()]; !ok {
+		log.Fatalf("默认网络 %s 未配置，请检查 default_network", GetDefaultNetwork())
+	}
+	log.Printf("已加载网络: %v，默认网络: %s", networkNames(), GetDefaultNetwork())
+}
+
+// checkLegacyRPCConfig 配置了 networks 时 benfen_rpc.url/urls 不会生效，同时配置视为配置错误
+func checkLegacyRPCConfig() error {
+	if AppConfig == nil || len(AppConfig.Networks) == 0 {
+		return nil
+	}
+	if AppConfig.BenfenRPC.URL != "" || len(AppConfig.BenfenRPC.URLs) > 0 {
+		return errors.New("已配置 networks，benfen_rpc.url/urls 不会生效，请将节点地址移到 networks.<网络>.urls")
+	}
+	return nil
+}
+
+// rpcTransport 按配置返回录制或回放 RPC 流量的 Transport，每个网络使用独立的子目录；都未配置时返回 nil
+func rpcTransport(network string) (http.RoundTripper, error) {
+	recordDir, replayDir := GetBenfenRPCRecordDir(), GetBenfenRPCReplayDir()
+	redactor := benfen.NewRedactor(GetBenfenRPCRedact())
+	switch {
+	case recordDir != "" && replayDir != "":
+		return nil, errors.New("record_dir 和 replay_dir 不能同时配置")
+	case replayDir != "":
+		log.Printf("网络 %s 回放 RPC 录制: %s", network, filepath.Join(replayDir, network))
+		return benfen.NewReplayTransport(filepath.Join(replayDir, network), redactor)
+	case recordDir != "":
+		log.Printf("网络 %s 录制 RPC 流量: %s", network, filepath.Join(recordDir, network))
+		return benfen.NewRecorder(filepath.Join(recordDir, network), redactor, nil)
+	default:
+		return nil, nil
+	}
+}
+
+// checkChainID 校验节点返回的链 ID 与配置一致，节点不可用时只记录日志
+func (n *Network) checkChainID() {
+	if n.Config.ChainID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), chainIDCheckTimeout)
+	defer cancel()
+
+	var chainID string
+	if err := n.Client.Call(ctx, benfen.MethodGetChainID, &chainID); err != nil {
+		log.Printf("网络 %s 查询链 ID 失败: %v", n.Name, err)
+		return
+	}
+	if chainID != n.Config.ChainID {
+		log.Printf("警告: 网络 %s 的节点链 ID 为 %s，与配置的 %s 不一致", n.Name, chainID, n.Config.ChainID)
+	}
+}
+
+// MaxGasBudget 该网络允许的最大 gas 预算，0 表示不限制
+func (n *Network) MaxGasBudget() uint64 {
+	return n.Config.Limits.MaxGasBudget
+}
+
+// MaxMintRecipients 单笔铸币交易最多的接收方数量
+func (n *Network) MaxMintRecipients() int {
+	if n.Config.Limits.MaxMintRecipients > 0 {
+		return n.Config.Limits.MaxMintRecipients
+	}
+	return mintMaxRecipients
+}
+
+// MetadataPackage 该网络上 metadata::update_metadata 的合约包地址
+func (n *Network) MetadataPackage() string {
+	if n.Config.MetadataPackage != "" {
+		return n.Config.MetadataPackage
+	}
+	return GetMetadataPackage()
+}
+
+// networkNames 返回排序后的网络名称
+func networkNames() []string {
+	names := make([]string, 0, len(networks))
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolveNetwork 按名称获取网络，名称为空时使用默认网络
+func resolveNetwork(name string) (*Network, error) {
+	if name == "" {
+		name = GetDefaultNetwork()
+	}
+	network, ok := networks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s，可用网络: %v", ErrUnknownNetwork, name, networkNames())
+	}
+	return network, nil
+}
+
+// selectNetwork 按请求中的网络名称设置请求 context，未知网络时返回 400。
+// 校验失败时已写出响应并返回 false。
+func selectNetwork(w http.ResponseWriter, r *http.Request, name string) (*http.Request, bool) {
+	network, err := resolveNetwork(name)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return r, false
+	}
+	return r.WithContext(withNetwork(r.Context(), network)), true
+}
+
+// selectCompileNetwork 选择发布或提交交易使用的网络，未指定时沿用编译记录的网络，
+// 与编译记录的网络不一致时返回 400。校验失败时已写出响应并返回 false。
+func selectCompileNetwork(w http.ResponseWriter, r *http.Request, name, compileID string) (*http.Request, bool) {
+	if compileID != "" {
+		if record, err := tokenStore.GetToken(compileID); err == nil && record.Network != "" {
+			if name == "" {
+				name = record.Network
+			} else if name != record.Network {
+				writeResponse(w, http.StatusBadRequest, TokenResponse{
+					Success: false,
+					Message: fmt.Sprintf("编译记录 %s 属于网络 %s，与请求的网络 %s 不一致", compileID, record.Network, name),
+				})
+				return r, false
+			}
+		}
+	}
+	return selectNetwork(w, r, name)
+}
+
+// lookupNetwork 按名称获取网络，名称为空或网络已从配置中移除时返回默认网络
+func lookupNetwork(name string) *Network {
+	if network, ok := networks[name]; ok {
+		return network
+	}
+	if name != "" {
+		log.Printf("网络 %s 未配置，使用默认网络 %s", name, GetDefaultNetwork())
+	}
+	return networks[GetDefaultNetwork()]
+}
+
+// 模板 Move.toml 中依赖的 rev 字段
+var templateRevision = regexp.MustCompile(`(\brev\s*=\s*")[^"]*(")`)
+
+// setTemplateRevision 将模板 Move.toml 中所有依赖的 rev 替换为指定版本
+func setTemplateRevision(projectDir, revision string) error {
+	manifest := filepath.Join(projectDir, "Move.toml")
+	content, err := os.ReadFile(manifest)
+	if err != nil {
+		return fmt.Errorf("读取 Move.toml 失败: %v", err)
+	}
+	if !templateRevision.Match(content) {
+		return fmt.Errorf("模板 Move.toml 中没有可替换的依赖版本 rev")
+	}
+	content = templateRevision.ReplaceAll(content, []byte("${1}"+revision+"${2}"))
+	return os.WriteFile(manifest, content, 0644)
+}
+
+type networkContextKey struct{}
+
+// withNetwork 将请求选择的网络放入 context，后续 RPC 调用使用该网络的客户端
+func withNetwork(ctx context.Context, network *Network) context.Context {
+	return context.WithValue(ctx, networkContextKey{}, network)
+}
+
+// networkFrom 获取 context 中的网络，未设置时返回默认网络
+func networkFrom(ctx context.Context) *Network {
+	if network, ok := ctx.Value(networkContextKey{}).(*Network); ok {
+		return network
+	}
+	return networks[GetDefaultNetwork()]
+}
+
+// rpcFor 获取 context 中网络的 RPC 客户端
+func rpcFor(ctx context.Context) *benfen.Client {
+	return networkFrom(ctx).Client
+}
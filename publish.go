@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"obc_coin_api/benfen"
+)
+
+// buildPublishTransaction 调用 unsafe_publish 构建发布交易，并按配置预执行估算 gas。
+// 未指定 gas 币时自动选择发送者的 BFC 币。
+func buildPublishTransaction(ctx context.Context, req PublishRequest) (*BuiltTransaction, error) {
+	opts := TxOptions{
+		Sender:              req.Sender,
+		Gas:                 req.Gas,
+		GasBudget:           req.GasBudget,
+		RebuildWithEstimate: req.RebuildWithEstimate,
+	}
+	return buildTransaction(ctx, opts, func(ctx context.Context, gas, gasBudget string) (*benfen.TransactionBlockBytes, error) {
+		publishReq := req
+		publishReq.Gas = gas
+		publishReq.GasBudget = gasBudget
+		return callUnsafePublish(ctx, publishReq)
+	})
+}
+
+// callUnsafePublish 调用 unsafe_publish 构建未签名的发布交易
+func callUnsafePublish(ctx context.Context, req PublishRequest) (*benfen.TransactionBlockBytes, error) {
+	var tx benfen.TransactionBlockBytes
+	params := []interface{}{req.Sender, req.CompiledModules, req.Dependencies, req.Gas, req.GasBudget}
+	if err := rpcFor(ctx).Call(ctx, benfen.MethodUnsafePublish, &tx, params...); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// writePublishError 返回发布交易构建失败的响应
+func writePublishError(w http.ResponseWriter, err error) {
+	status, response := publishErrorResponse(err)
+	writeResponse(w, status, response)
+}
+
+// publishErrorResponse 生成发布交易构建失败对应的 HTTP 状态码和响应
+func publishErrorResponse(err error) (int, TokenResponse) {
+	return txBuildErrorResponse("构建发布交易失败", err)
+}
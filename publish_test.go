@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"obc_coin_api/benfen"
+)
+
+// 录制 mockFixtureDir 时使用的发送者，录制文件中已脱敏
+const mockFixtureSender = "0x6f0f9a9a72f7d48b8fcbfa09ebb61123d847aaad5760297d68c64795bad514b1"
+
+// mockFixtureDir 由仓库内的模拟节点（mock-rpc）录制的发布流程，redact 配置为 mockFixtureSender。
+// 回放只能说明客户端与模拟节点一致，不能代替真实节点的协议覆盖
+const mockFixtureDir = "testdata/rpc/mocknode"
+
+// useMockReplayNetwork 将默认网络替换为回放模拟节点录制文件的 devnet，测试结束后恢复
+func useMockReplayNetwork(t *testing.T) {
+	t.Helper()
+	replay, err := benfen.NewReplayTransport(mockFixtureDir, benfen.NewRedactor([]string{mockFixtureSender}))
+	if err != nil {
+		t.Fatalf("加载录制文件失败: %v", err)
+	}
+
+	savedConfig, savedNetworks := AppConfig, networks
+	t.Cleanup(func() { AppConfig, networks = savedConfig, savedNetworks })
+
+	AppConfig = &Config{DefaultNetwork: "devnet"}
+	networks = map[string]*Network{
+		"devnet": {
+			Name: "devnet",
+			Client: benfen.NewClient(benfen.Options{
+				URLs:      []string{"http://replay.invalid/"},
+				Timeout:   5 * time.Second,
+				Transport: replay,
+			}),
+		},
+	}
+}
+
+func TestPublishTokenMockReplay(t *testing.T) {
+	useMockReplayNetwork(t)
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"sender":                mockFixtureSender,
+		"compiled_modules":      []string{base64.StdEncoding.EncodeToString([]byte("fast_coin module bytecode for fixtures"))},
+		"dependencies":          []string{"0x1", "0x2"},
+		"gas_budget":            "10000000",
+		"rebuild_with_estimate": true,
+	})
+	rec := httptest.NewRecorder()
+	publishToken(rec, httptest.NewRequest(http.MethodPost, "/api/token/publish", bytes.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码为 %d，应为 200: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Success bool             `json:"success"`
+		Data    BuiltTransaction `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	built := resp.Data
+	if !resp.Success {
+		t.Fatalf("发布交易构建失败: %s", rec.Body.String())
+	}
+
+	const gasCoinID = "0x0000000000000000000000000000000000000000000000000000000000001001"
+	if built.Network != "devnet" || built.Sender != mockFixtureSender {
+		t.Fatalf("网络和发送者为 %s/%s", built.Network, built.Sender)
+	}
+	if built.GasCoin == nil || built.GasCoin.ObjectID != gasCoinID || !built.GasCoin.AutoSelected {
+		t.Fatalf("gas 币应自动选择 %s: %+v", gasCoinID, built.GasCoin)
+	}
+	if len(built.Gas) != 1 || built.Gas[0].ObjectID != gasCoinID {
+		t.Fatalf("交易的 gas 对象为 %+v，应为 %s", built.Gas, gasCoinID)
+	}
+	if built.GasEstimate == nil || built.GasEstimate.Status != "success" || built.GasEstimate.RecommendedBudget != 6000000 {
+		t.Fatalf("gas 估算不符合录制: %+v", built.GasEstimate)
+	}
+	if !built.Rebuilt || built.GasBudget != "6000000" {
+		t.Fatalf("应按推荐预算 6000000 重新构建，实际 rebuilt=%v gas_budget=%s", built.Rebuilt, built.GasBudget)
+	}
+
+	// 重新构建的交易字节来自最后一次 unsafe_publish 的录制
+	var rebuilt struct {
+		Response struct {
+			Result benfen.TransactionBlockBytes `json:"result"`
+		} `json:"response"`
+	}
+	data, err := os.ReadFile(filepath.Join(mockFixtureDir, "0004_unsafe_publish.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &rebuilt); err != nil {
+		t.Fatal(err)
+	}
+	if built.TxBytes != rebuilt.Response.Result.TxBytes {
+		t.Fatalf("交易字节与录制不一致")
+	}
+}
+
+// 录制文件中不能出现录制时的发送者，包括 base64 交易字节中的文本和原始字节形式
+func TestMockFixturesRedacted(t *testing.T) {
+	secret := strings.TrimPrefix(mockFixtureSender, "0x")
+	secretBytes, _ := hex.DecodeString(secret)
+	files, err := filepath.Glob(filepath.Join(mockFixtureDir, "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("没有录制文件: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(strings.ToLower(string(data)), secret) {
+			t.Errorf("%s 包含未脱敏的发送者", file)
+		}
+		for _, field := range strings.Split(string(data), `"`) {
+			decoded, err := base64.StdEncoding.DecodeString(field)
+			if err != nil || len(field) < 40 {
+				continue
+			}
+			if bytes.Contains(bytes.ToLower(decoded), []byte(secret)) || bytes.Contains(decoded, secretBytes) {
+				t.Errorf("%s 的 base64 内容包含未脱敏的发送者", file)
+			}
+		}
+	}
+}
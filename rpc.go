@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"obc_coin_api/benfen"
+)
+
+// rpcErrorStatus 将 RPC 调用错误映射为 HTTP 状态码
+func rpcErrorStatus(err error) int {
+	var rpcErr *benfen.RPCError
+	if errors.As(err, &rpcErr) {
+		switch {
+		case rpcErr.Code == -32600 || rpcErr.Code == -32602:
+			// 请求或参数无效
+			return http.StatusBadRequest
+		case rpcErr.Code <= -32000 && rpcErr.Code >= -32099:
+			// 节点执行失败，如余额不足、对象不存在
+			return http.StatusUnprocessableEntity
+		default:
+			return http.StatusBadGateway
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// writeRPCError 以标准响应格式返回 RPC 调用错误，JSON-RPC 错误对象放在 data 中
+func writeRPCError(w http.ResponseWriter, message string, err error) {
+	status, response := rpcErrorResponse(message, err)
+	writeResponse(w, status, response)
+}
+
+// rpcErrorResponse 生成 RPC 调用错误对应的 HTTP 状态码和响应
+func rpcErrorResponse(message string, err error) (int, TokenResponse) {
+	response := TokenResponse{
+		Success: false,
+		Message: fmt.Sprintf("%s: %v", message, err),
+	}
+	var rpcErr *benfen.RPCError
+	if errors.As(err, &rpcErr) {
+		response.Data = map[string]interface{}{"rpc_error": rpcErr}
+	}
+	return rpcErrorStatus(err), response
+}
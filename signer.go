@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"obc_coin_api/benfen"
+	"obc_coin_api/keystore"
+)
+
+// 服务端签名密钥，未启用时为 nil
+var serverSigner *keystore.Key
+
+// initSigner 按配置加载服务端签名密钥，启用但加载失败时拒绝启动
+func initSigner() {
+	if !IsSignerEnabled() {
+		return
+	}
+
+	passphrase, err := readSignerPassphrase()
+	if err != nil {
+		log.Fatalf("读取密钥口令失败: %v", err)
+	}
+	key, err := keystore.Load(GetSignerKeystorePath(), passphrase)
+	if err != nil {
+		log.Fatalf("加载签名密钥失败: %v", err)
+	}
+	serverSigner = key
+	log.Printf("服务端签名已启用: %s (%s)", key.Address(), key.Scheme())
+}
+
+// readSignerPassphrase 从口令文件或环境变量读取密钥口令
+func readSignerPassphrase() (string, error) {
+	if path := GetSignerPassphraseFile(); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	env := GetSignerPassphraseEnv()
+	passphrase := os.Getenv(env)
+	if passphrase == "" {
+		return "", fmt.Errorf("环境变量 %s 未设置", env)
+	}
+	return passphrase, nil
+}
+
+// errSignerDisabled 未启用服务端签名
+var errSignerDisabled = errors.New("服务端签名未启用")
+
+// signAndExecute 使用服务端密钥签名并提交交易，提交前开始跟踪交易确认状态
+func signAndExecute(ctx context.Context, built *BuiltTransaction, kind, tokenID string) (*ExecutionResult, error) {
+	if serverSigner == nil {
+		return nil, errSignerDisabled
+	}
+	if built.GasCoin != nil {
+		defer gasCoinLocks.release(built.GasCoin.ObjectID)
+	}
+
+	signature, err := serverSigner.SignTransaction(built.TxBytes)
+	if err != nil {
+		return nil, fmt.Errorf("签名交易失败: %v", err)
+	}
+	digest, err := benfen.TransactionDigest(built.TxBytes)
+	if err != nil {
+		return nil, err
+	}
+	tracker.Track(ctx, digest, kind, tokenID, built.Sender)
+
+	resp, err := executeTransaction(ctx, built.TxBytes, []string{signature}, benfen.WaitForLocalExecution)
+	if err != nil {
+		var rpcErr *benfen.RPCError
+		if errors.As(err, &rpcErr) {
+			tracker.Fail(digest, err)
+		}
+		return nil, err
+	}
+
+	result := newExecutionResult(resp)
+	tracker.Resolve(digest, result)
+	return result, nil
+}
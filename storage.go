@@ -0,0 +1,485 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 代币记录状态
+const (
+	TokenStatusCompileFailed = "compile_failed"
+	TokenStatusCompiled      = "compiled"
+	TokenStatusSubmitted     = "submitted"
+	TokenStatusConfirmed     = "confirmed"
+	TokenStatusFailed        = "failed"
+)
+
+// 交易跟踪状态
+const (
+	TxStatusPending = "pending"
+	TxStatusSuccess = "success"
+	TxStatusFailure = "failure"
+	TxStatusTimeout = "timeout"
+)
+
+// 交易类型
+const (
+	TxKindPublish        = "publish"
+	TxKindMetadataUpdate = "metadata_update"
+	TxKindMint           = "mint"
+	TxKindBurn           = "burn"
+	TxKindCapTransfer    = "treasury_cap_transfer"
+	TxKindMetadataFreeze = "metadata_freeze"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("记录不存在")
+
+// TokenRecord 一次代币编译及其发布结果
+type TokenRecord struct {
+	ID              string       `json:"id"`
+	Request         TokenRequest `json:"request"`
+	Network         string       `json:"network,omitempty"`
+	Status          string       `json:"status"`
+	CompilerVersion string       `json:"compiler_version"`
+	// TemplateVersion 编译时代币模板内容的摘要，TemplateRevision 为网络配置替换的依赖版本
+	TemplateVersion  string    `json:"template_version,omitempty"`
+	TemplateRevision string    `json:"template_revision,omitempty"`
+	Modules          []string  `json:"modules"`
+	Dependencies     []string  `json:"dependencies"`
+	Sender           string    `json:"sender,omitempty"`
+	TxDigest         string    `json:"tx_digest,omitempty"`
+	PackageID        string    `json:"package_id,omitempty"`
+	TreasuryCapID    string    `json:"treasury_cap_id,omitempty"`
+	CoinMetadataID   string    `json:"coin_metadata_id,omitempty"`
+	CoinType         string    `json:"coin_type,omitempty"`
+	Error            string    `json:"error,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TxRecord 已提交交易的确认状态
+type TxRecord struct {
+	Digest      string    `json:"digest"`
+	Kind        string    `json:"kind,omitempty"`
+	Network     string    `json:"network,omitempty"`
+	TokenID     string    `json:"token_id,omitempty"`
+	Sender      string    `json:"sender,omitempty"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	Error       string    `json:"error,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// 代币列表的排序字段
+const (
+	TokenSortCreatedAt = "created_at"
+	TokenSortUpdatedAt = "updated_at"
+	TokenSortSymbol    = "symbol"
+	TokenSortName      = "name"
+)
+
+// TokenQuery 代币记录的查询条件，字段为空时不过滤
+type TokenQuery struct {
+	Symbol     string
+	NamePrefix string
+	Sender     string
+	Network    string
+	Status     string
+	// CreatedFrom 包含，CreatedTo 不包含
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Sort        string
+	Desc        bool
+	Limit       int
+	// After 上一页返回的游标，为空时从第一条开始
+	After *TokenCursor
+}
+
+// TokenCursor 分页游标，记录上一页最后一条记录的排序值和 ID，排序方式必须与生成时一致
+type TokenCursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+// tokenSortValue 记录在排序字段上的值，时间以 Unix 微秒的十进制字符串表示
+func tokenSortValue(record *TokenRecord, sort string) string {
+	switch sort {
+	case TokenSortUpdatedAt:
+		return strconv.FormatInt(record.UpdatedAt.UnixMicro(), 10)
+	case TokenSortSymbol:
+		return record.Request.Symbol
+	case TokenSortName:
+		return record.Request.Name
+	default:
+		return strconv.FormatInt(record.CreatedAt.UnixMicro(), 10)
+	}
+}
+
+// tokenCursorFor 生成指向记录之后的游标
+func tokenCursorFor(record *TokenRecord, query TokenQuery) *TokenCursor {
+	return &TokenCursor{Sort: query.Sort, Desc: query.Desc, Value: tokenSortValue(record, query.Sort), ID: record.ID}
+}
+
+// TokenStore 代币记录存储
+type TokenStore interface {
+	// SaveToken 新增或更新记录
+	SaveToken(record *TokenRecord) error
+	// GetToken 按 ID 获取记录，不存在时返回 ErrNotFound
+	GetToken(id string) (*TokenRecord, error)
+	// FindTokenByCoinType 按网络和代币类型获取已发布的记录，不存在时返回 ErrNotFound
+	FindTokenByCoinType(network, coinType string) (*TokenRecord, error)
+	// SaveTx 新增或更新交易记录
+	SaveTx(record *TxRecord) error
+	// GetTx 按交易摘要获取记录，不存在时返回 ErrNotFound
+	GetTx(digest string) (*TxRecord, error)
+	// ListPendingTx 列出仍在等待确认的交易
+	ListPendingTx() ([]*TxRecord, error)
+	// ListTokens 按条件分页列出记录，返回下一页游标，没有更多记录时游标为 nil
+	ListTokens(query TokenQuery) ([]*TokenRecord, *TokenCursor, error)
+	// ListTokenTxs 列出关联到代币记录的交易，按提交时间排序
+	ListTokenTxs(tokenID string) ([]*TxRecord, error)
+}
+
+// 全局代币记录存储
+var tokenStore TokenStore
+
+// tokenLocks 每个编译记录一把锁，串行化记录的读改写，避免执行结果和后台确认相互覆盖
+var tokenLocks = struct {
+	sync.Mutex
+	locks map[string]*tokenLock
+}{locks: make(map[string]*tokenLock)}
+
+// tokenLock 编译记录的锁，refs 为持有或等待的请求数，为 0 时删除
+type tokenLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lockToken 锁定编译记录，返回解锁函数
+func lockToken(id string) func() {
+	tokenLocks.Lock()
+	lock := tokenLocks.locks[id]
+	if lock == nil {
+		lock = &tokenLock{}
+		tokenLocks.locks[id] = lock
+	}
+	lock.refs++
+	tokenLocks.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		tokenLocks.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(tokenLocks.locks, id)
+		}
+		tokenLocks.Unlock()
+	}
+}
+
+// updateToken 在编译记录的锁内读取、修改并保存记录，update 返回 false 时不保存
+func updateToken(id string, update func(record *TokenRecord) bool) error {
+	unlock := lockToken(id)
+	defer unlock()
+
+	record, err := tokenStore.GetToken(id)
+	if err != nil {
+		return err
+	}
+	if !update(record) {
+		return nil
+	}
+	return tokenStore.SaveToken(record)
+}
+
+// initStorage 按配置打开代币记录存储，数据库存储在启动时执行迁移
+func initStorage() {
+	var err error
+	switch driver := GetStorageDriver(); driver {
+	case StorageDriverFile:
+		tokenStore, err = openFileStore(GetStoragePath())
+		log.Printf("代币记录存储: %s", GetStoragePath())
+	case StorageDriverSQLite, StorageDriverPostgres:
+		var store *sqlStore
+		if driver == StorageDriverSQLite {
+			store, err = openSQLiteStore(GetStorageSQLitePath())
+			log.Printf("代币记录存储: SQLite %s", GetStorageSQLitePath())
+		} else {
+			store, err = openPostgresStore()
+			log.Printf("代币记录存储: PostgreSQL %s:%d/%s", GetDatabaseHost(), GetDatabasePort(), GetDatabaseName())
+		}
+		if err == nil {
+			err = store.importFileStore(GetStoragePath())
+		}
+		tokenStore = store
+	default:
+		err = fmt.Errorf("未知的存储驱动 %s", driver)
+	}
+	if err != nil {
+		log.Fatalf("打开代币记录存储失败: %v", err)
+	}
+}
+
+// newID 生成随机记录 ID
+func newID() string {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// fileContents 存储文件内容
+type fileContents struct {
+	Tokens       []*TokenRecord `json:"tokens"`
+	Transactions []*TxRecord    `json:"transactions"`
+}
+
+// fileStore 以 JSON 文件保存全部记录，每次修改后整体重写
+type fileStore struct {
+	mu      sync.RWMutex
+	path    string
+	records map[string]*TokenRecord
+	txs     map[string]*TxRecord
+}
+
+// openFileStore 打开文件存储，文件不存在时创建空存储
+func openFileStore(path string) (*fileStore, error) {
+	s := &fileStore{
+		path:    path,
+		records: make(map[string]*TokenRecord),
+		txs:     make(map[string]*TxRecord),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var contents fileContents
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return nil, fmt.Errorf("解析存储文件失败: %v", err)
+	}
+	for _, record := range contents.Tokens {
+		s.records[record.ID] = record
+	}
+	for _, record := range contents.Transactions {
+		s.txs[record.Digest] = record
+	}
+	return s, nil
+}
+
+// SaveToken 新增或更新记录
+func (s *fileStore) SaveToken(record *TokenRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	record.UpdatedAt = now
+
+	copied := *record
+	s.records[record.ID] = &copied
+	return s.flush()
+}
+
+// GetToken 按 ID 获取记录
+func (s *fileStore) GetToken(id string) (*TokenRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// FindTokenByCoinType 按网络和代币类型获取已发布的记录
+func (s *fileStore) FindTokenByCoinType(network, coinType string) (*TokenRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, record := range s.records {
+		if record.Network == network && record.CoinType == coinType {
+			copied := *record
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// SaveTx 新增或更新交易记录
+func (s *fileStore) SaveTx(record *TxRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if record.SubmittedAt.IsZero() {
+		record.SubmittedAt = now
+	}
+	record.UpdatedAt = now
+
+	copied := *record
+	s.txs[record.Digest] = &copied
+	return s.flush()
+}
+
+// GetTx 按交易摘要获取记录
+func (s *fileStore) GetTx(digest string) (*TxRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.txs[digest]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// ListPendingTx 列出仍在等待确认的交易
+func (s *fileStore) ListPendingTx() ([]*TxRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var pending []*TxRecord
+	for _, record := range s.txs {
+		if record.Status == TxStatusPending {
+			copied := *record
+			pending = append(pending, &copied)
+		}
+	}
+	return pending, nil
+}
+
+// ListTokens 按条件分页列出记录
+func (s *fileStore) ListTokens(query TokenQuery) ([]*TokenRecord, *TokenCursor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	numeric := query.Sort == TokenSortCreatedAt || query.Sort == TokenSortUpdatedAt
+	// less 按排序字段和 ID 比较，返回 a 是否排在 b 之前
+	less := func(aValue, aID, bValue, bID string) bool {
+		if aValue != bValue {
+			if numeric {
+				a, _ := strconv.ParseInt(aValue, 10, 64)
+				b, _ := strconv.ParseInt(bValue, 10, 64)
+				return (a < b) != query.Desc
+			}
+			return (aValue < bValue) != query.Desc
+		}
+		return (aID < bID) != query.Desc
+	}
+
+	var matched []*TokenRecord
+	for _, record := range s.records {
+		if !query.matches(record) {
+			continue
+		}
+		if query.After != nil && !less(query.After.Value, query.After.ID, tokenSortValue(record, query.Sort), record.ID) {
+			continue
+		}
+		matched = append(matched, record)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return less(tokenSortValue(matched[i], query.Sort), matched[i].ID, tokenSortValue(matched[j], query.Sort), matched[j].ID)
+	})
+
+	var next *TokenCursor
+	if len(matched) > query.Limit {
+		matched = matched[:query.Limit]
+		next = tokenCursorFor(matched[len(matched)-1], query)
+	}
+	records := make([]*TokenRecord, len(matched))
+	for i, record := range matched {
+		copied := *record
+		records[i] = &copied
+	}
+	return records, next, nil
+}
+
+// matches 内存中判断记录是否满足过滤条件，与 SQL 存储的条件一致
+func (q TokenQuery) matches(record *TokenRecord) bool {
+	switch {
+	case q.Symbol != "" && !strings.EqualFold(record.Request.Symbol, q.Symbol):
+		return false
+	case q.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(record.Request.Name), strings.ToLower(q.NamePrefix)):
+		return false
+	case q.Sender != "" && record.Sender != q.Sender:
+		return false
+	case q.Network != "" && record.Network != q.Network:
+		return false
+	case q.Status != "" && record.Status != q.Status:
+		return false
+	case !q.CreatedFrom.IsZero() && record.CreatedAt.Before(q.CreatedFrom):
+		return false
+	case !q.CreatedTo.IsZero() && !record.CreatedAt.Before(q.CreatedTo):
+		return false
+	}
+	return true
+}
+
+// ListTokenTxs 列出关联到代币记录的交易
+func (s *fileStore) ListTokenTxs(tokenID string) ([]*TxRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var txs []*TxRecord
+	for _, record := range s.txs {
+		if record.TokenID == tokenID {
+			copied := *record
+			txs = append(txs, &copied)
+		}
+	}
+	sort.Slice(txs, func(i, j int) bool { return txs[i].SubmittedAt.Before(txs[j].SubmittedAt) })
+	return txs, nil
+}
+
+// flush 将全部记录写入临时文件后替换，调用方需持有写锁
+func (s *fileStore) flush() error {
+	contents := fileContents{
+		Tokens:       make([]*TokenRecord, 0, len(s.records)),
+		Transactions: make([]*TxRecord, 0, len(s.txs)),
+	}
+	for _, record := range s.records {
+		contents.Tokens = append(contents.Tokens, record)
+	}
+	for _, record := range s.txs {
+		contents.Transactions = append(contents.Transactions, record)
+	}
+	data, err := json.MarshalIndent(contents, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
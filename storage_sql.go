@@ -0,0 +1,531 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+// 存储驱动
+const (
+	StorageDriverSQLite   = "sqlite"
+	StorageDriverPostgres = "postgres"
+	StorageDriverFile     = "file"
+)
+
+// 多实例同时启动时串行执行迁移的 PostgreSQL advisory lock 键
+const migrationLockKey = 48104812
+
+// migration 一次版本化的表结构变更，已执行的版本记录在 schema_migrations 中，不能修改只能追加
+type migration struct {
+	Version     int
+	Description string
+	Statements  []string
+}
+
+// migrations 按版本顺序执行，SQL 同时兼容 SQLite 和 PostgreSQL。
+// 时间以 Unix 微秒保存，便于两种数据库统一排序和范围查询
+var migrations = []migration{
+	{
+		Version:     1,
+		Description: "代币和交易记录",
+		Statements: []string{
+			`CREATE TABLE tokens (
+				id                TEXT PRIMARY KEY,
+				network           TEXT NOT NULL DEFAULT '',
+				status            TEXT NOT NULL,
+				symbol            TEXT NOT NULL DEFAULT '',
+				name              TEXT NOT NULL DEFAULT '',
+				decimals          INTEGER NOT NULL DEFAULT 0,
+				request           TEXT NOT NULL,
+				compiler_version  TEXT NOT NULL DEFAULT '',
+				template_version  TEXT NOT NULL DEFAULT '',
+				template_revision TEXT NOT NULL DEFAULT '',
+				modules           TEXT NOT NULL DEFAULT '[]',
+				dependencies      TEXT NOT NULL DEFAULT '[]',
+				sender            TEXT NOT NULL DEFAULT '',
+				tx_digest         TEXT NOT NULL DEFAULT '',
+				package_id        TEXT NOT NULL DEFAULT '',
+				treasury_cap_id   TEXT NOT NULL DEFAULT '',
+				coin_metadata_id  TEXT NOT NULL DEFAULT '',
+				coin_type         TEXT NOT NULL DEFAULT '',
+				error             TEXT NOT NULL DEFAULT '',
+				created_at        BIGINT NOT NULL,
+				updated_at        BIGINT NOT NULL
+			)`,
+			`CREATE INDEX tokens_coin_type ON tokens (coin_type)`,
+			`CREATE INDEX tokens_created_at ON tokens (created_at)`,
+			`CREATE TABLE transactions (
+				digest       TEXT PRIMARY KEY,
+				kind         TEXT NOT NULL DEFAULT '',
+				network      TEXT NOT NULL DEFAULT '',
+				token_id     TEXT NOT NULL DEFAULT '',
+				sender       TEXT NOT NULL DEFAULT '',
+				status       TEXT NOT NULL,
+				attempts     INTEGER NOT NULL DEFAULT 0,
+				error        TEXT NOT NULL DEFAULT '',
+				submitted_at BIGINT NOT NULL,
+				updated_at   BIGINT NOT NULL
+			)`,
+			`CREATE INDEX transactions_status ON transactions (status)`,
+			`CREATE INDEX transactions_token_id ON transactions (token_id)`,
+		},
+	},
+	{
+		Version:     2,
+		Description: "代币列表查询索引",
+		Statements: []string{
+			`CREATE INDEX tokens_sender ON tokens (sender, created_at)`,
+			`CREATE INDEX tokens_symbol ON tokens (symbol)`,
+		},
+	},
+	{
+		Version:     3,
+		Description: "按符号不区分大小写查询的表达式索引",
+		Statements: []string{
+			`CREATE INDEX tokens_symbol_upper ON tokens (UPPER(symbol))`,
+		},
+	},
+}
+
+// sqlStore 基于 database/sql 的存储，支持 SQLite 和 PostgreSQL
+type sqlStore struct {
+	db     *sql.DB
+	driver string
+}
+
+// openSQLiteStore 打开嵌入式 SQLite 数据库，文件不存在时创建
+func openSQLiteStore(path string) (*sqlStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	dsn := "file:" + path + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite 同一时间只允许一个写入者，单连接避免 SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+	return newSQLStore(db, StorageDriverSQLite)
+}
+
+// openPostgresStore 按 database 配置连接 PostgreSQL
+func openPostgresStore() (*sqlStore, error) {
+	db, err := sql.Open("pgx", GetDatabaseURL())
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(10)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	return newSQLStore(db, StorageDriverPostgres)
+}
+
+// newSQLStore 检查连接并执行未应用的迁移
+func newSQLStore(db *sql.DB, driver string) (*sqlStore, error) {
+	s := &sqlStore{db: db, driver: driver}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接数据库失败: %v", err)
+	}
+	if err := s.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("执行数据库迁移失败: %v", err)
+	}
+	return s, nil
+}
+
+// rebind 将 ? 占位符转换为 PostgreSQL 的 $n
+func (s *sqlStore) rebind(query string) string {
+	if s.driver != StorageDriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// migrate 按版本执行未应用的迁移，每个版本在单独的事务中执行
+func (s *sqlStore) migrate(ctx context.Context) error {
+	if s.driver == StorageDriverPostgres {
+		conn, err := s.db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+			return err
+		}
+		defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version     INTEGER PRIMARY KEY,
+		description TEXT NOT NULL,
+		applied_at  BIGINT NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&current); err != nil {
+		return err
+	}
+	if latest := migrations[len(migrations)-1].Version; current > latest {
+		return fmt.Errorf("数据库版本 %d 高于程序支持的版本 %d，请升级服务", current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, statement := range m.Statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("版本 %d: %v", m.Version, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, s.rebind("INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)"),
+			m.Version, m.Description, time.Now().UnixMicro()); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("数据库迁移到版本 %d: %s", m.Version, m.Description)
+	}
+	return nil
+}
+
+// importFileStore 数据库为空时导入旧的 JSON 文件存储中的记录，只执行一次
+func (s *sqlStore) importFileStore(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return nil
+	}
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM tokens").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	old, err := openFileStore(path)
+	if err != nil {
+		return err
+	}
+	for _, record := range old.records {
+		if err := s.SaveToken(record); err != nil {
+			return err
+		}
+	}
+	for _, record := range old.txs {
+		if err := s.SaveTx(record); err != nil {
+			return err
+		}
+	}
+	log.Printf("已从 %s 导入 %d 条代币记录和 %d 条交易记录", path, len(old.records), len(old.txs))
+	return nil
+}
+
+const tokenColumns = `id, network, status, symbol, name, decimals, request, compiler_version, template_version, template_revision,
+	modules, dependencies, sender, tx_digest, package_id, treasury_cap_id, coin_metadata_id, coin_type, error, created_at, updated_at`
+
+// SaveToken 新增或更新记录
+func (s *sqlStore) SaveToken(record *TokenRecord) error {
+	now := time.Now()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	record.UpdatedAt = now
+
+	request, err := json.Marshal(record.Request)
+	if err != nil {
+		return err
+	}
+	modules, err := json.Marshal(emptyIfNil(record.Modules))
+	if err != nil {
+		return err
+	}
+	dependencies, err := json.Marshal(emptyIfNil(record.Dependencies))
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(s.rebind(`INSERT INTO tokens (`+tokenColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			network = excluded.network, status = excluded.status, symbol = excluded.symbol, name = excluded.name,
+			decimals = excluded.decimals, request = excluded.request, compiler_version = excluded.compiler_version,
+			template_version = excluded.template_version, template_revision = excluded.template_revision,
+			modules = excluded.modules, dependencies = excluded.dependencies, sender = excluded.sender,
+			tx_digest = excluded.tx_digest, package_id = excluded.package_id, treasury_cap_id = excluded.treasury_cap_id,
+			coin_metadata_id = excluded.coin_metadata_id, coin_type = excluded.coin_type, error = excluded.error,
+			updated_at = excluded.updated_at`),
+		record.ID, record.Network, record.Status, record.Request.Symbol, record.Request.Name, record.Request.Decimal,
+		string(request), record.CompilerVersion, record.TemplateVersion, record.TemplateRevision,
+		string(modules), string(dependencies), record.Sender, record.TxDigest, record.PackageID,
+		record.TreasuryCapID, record.CoinMetadataID, record.CoinType, record.Error,
+		record.CreatedAt.UnixMicro(), record.UpdatedAt.UnixMicro())
+	return err
+}
+
+// emptyIfNil 保证数组字段序列化为 [] 而不是 null
+func emptyIfNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// scanner 兼容 *sql.Row 和 *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanToken 读取一行代币记录
+func scanToken(row scanner) (*TokenRecord, error) {
+	var record TokenRecord
+	var symbol, name string
+	var decimals int
+	var request, modules, dependencies string
+	var createdAt, updatedAt int64
+	err := row.Scan(&record.ID, &record.Network, &record.Status, &symbol, &name, &decimals, &request,
+		&record.CompilerVersion, &record.TemplateVersion, &record.TemplateRevision, &modules, &dependencies,
+		&record.Sender, &record.TxDigest, &record.PackageID, &record.TreasuryCapID, &record.CoinMetadataID,
+		&record.CoinType, &record.Error, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(request), &record.Request); err != nil {
+		return nil, fmt.Errorf("解析记录 %s 的请求失败: %v", record.ID, err)
+	}
+	if err := json.Unmarshal([]byte(modules), &record.Modules); err != nil {
+		return nil, fmt.Errorf("解析记录 %s 的模块失败: %v", record.ID, err)
+	}
+	if err := json.Unmarshal([]byte(dependencies), &record.Dependencies); err != nil {
+		return nil, fmt.Errorf("解析记录 %s 的依赖失败: %v", record.ID, err)
+	}
+	record.CreatedAt = time.UnixMicro(createdAt)
+	record.UpdatedAt = time.UnixMicro(updatedAt)
+	return &record, nil
+}
+
+// GetToken 按 ID 获取记录
+func (s *sqlStore) GetToken(id string) (*TokenRecord, error) {
+	return scanToken(s.db.QueryRow(s.rebind("SELECT "+tokenColumns+" FROM tokens WHERE id = ?"), id))
+}
+
+// FindTokenByCoinType 按网络和代币类型获取已发布的记录
+func (s *sqlStore) FindTokenByCoinType(network, coinType string) (*TokenRecord, error) {
+	return scanToken(s.db.QueryRow(s.rebind("SELECT "+tokenColumns+" FROM tokens WHERE network = ? AND coin_type = ? ORDER BY created_at LIMIT 1"), network, coinType))
+}
+
+const txColumns = `digest, kind, network, token_id, sender, status, attempts, error, submitted_at, updated_at`
+
+// SaveTx 新增或更新交易记录
+func (s *sqlStore) SaveTx(record *TxRecord) error {
+	now := time.Now()
+	if record.SubmittedAt.IsZero() {
+		record.SubmittedAt = now
+	}
+	record.UpdatedAt = now
+
+	_, err := s.db.Exec(s.rebind(`INSERT INTO transactions (`+txColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (digest) DO UPDATE SET
+			kind = excluded.kind, network = excluded.network, token_id = excluded.token_id, sender = excluded.sender,
+			status = excluded.status, attempts = excluded.attempts, error = excluded.error, updated_at = excluded.updated_at`),
+		record.Digest, record.Kind, record.Network, record.TokenID, record.Sender, record.Status,
+		record.Attempts, record.Error, record.SubmittedAt.UnixMicro(), record.UpdatedAt.UnixMicro())
+	return err
+}
+
+// scanTx 读取一行交易记录
+func scanTx(row scanner) (*TxRecord, error) {
+	var record TxRecord
+	var submittedAt, updatedAt int64
+	err := row.Scan(&record.Digest, &record.Kind, &record.Network, &record.TokenID, &record.Sender,
+		&record.Status, &record.Attempts, &record.Error, &submittedAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	record.SubmittedAt = time.UnixMicro(submittedAt)
+	record.UpdatedAt = time.UnixMicro(updatedAt)
+	return &record, nil
+}
+
+// GetTx 按交易摘要获取记录
+func (s *sqlStore) GetTx(digest string) (*TxRecord, error) {
+	return scanTx(s.db.QueryRow(s.rebind("SELECT "+txColumns+" FROM transactions WHERE digest = ?"), digest))
+}
+
+// ListPendingTx 列出仍在等待确认的交易
+func (s *sqlStore) ListPendingTx() ([]*TxRecord, error) {
+	rows, err := s.db.Query(s.rebind("SELECT "+txColumns+" FROM transactions WHERE status = ? ORDER BY submitted_at"), TxStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pending []*TxRecord
+	for rows.Next() {
+		record, err := scanTx(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, record)
+	}
+	return pending, rows.Err()
+}
+
+// tokenSortColumns 排序字段对应的列，字段名来自 TokenQuery 且已校验
+var tokenSortColumns = map[string]string{
+	TokenSortCreatedAt: "created_at",
+	TokenSortUpdatedAt: "updated_at",
+	TokenSortSymbol:    "symbol",
+	TokenSortName:      "name",
+}
+
+// likeEscaper 转义 LIKE 模式中的通配符
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListTokens 按条件分页列出记录，使用 (排序值, ID) 的键集分页，翻页时不受新增记录影响
+func (s *sqlStore) ListTokens(query TokenQuery) ([]*TokenRecord, *TokenCursor, error) {
+	column, ok := tokenSortColumns[query.Sort]
+	if !ok {
+		return nil, nil, fmt.Errorf("不支持的排序字段: %s", query.Sort)
+	}
+
+	var where []string
+	var args []interface{}
+	if query.Symbol != "" {
+		// 与 tokens_symbol_upper 索引的表达式一致
+		where = append(where, "UPPER(symbol) = UPPER(?)")
+		args = append(args, query.Symbol)
+	}
+	if query.NamePrefix != "" {
+		where = append(where, `LOWER(name) LIKE ? ESCAPE '\'`)
+		args = append(args, likeEscaper.Replace(strings.ToLower(query.NamePrefix))+"%")
+	}
+	if query.Sender != "" {
+		where = append(where, "sender = ?")
+		args = append(args, query.Sender)
+	}
+	if query.Network != "" {
+		where = append(where, "network = ?")
+		args = append(args, query.Network)
+	}
+	if query.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, query.Status)
+	}
+	if !query.CreatedFrom.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, query.CreatedFrom.UnixMicro())
+	}
+	if !query.CreatedTo.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, query.CreatedTo.UnixMicro())
+	}
+
+	op, direction := ">", "ASC"
+	if query.Desc {
+		op, direction = "<", "DESC"
+	}
+	if query.After != nil {
+		var value interface{} = query.After.Value
+		if query.Sort == TokenSortCreatedAt || query.Sort == TokenSortUpdatedAt {
+			micros, err := strconv.ParseInt(query.After.Value, 10, 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("无效的游标: %v", err)
+			}
+			value = micros
+		}
+		where = append(where, fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, op))
+		args = append(args, value, value, query.After.ID)
+	}
+
+	stmt := "SELECT " + tokenColumns + " FROM tokens"
+	if len(where) > 0 {
+		stmt += " WHERE " + strings.Join(where, " AND ")
+	}
+	stmt += fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s LIMIT ?", column, direction)
+	// 多取一条判断是否还有下一页
+	args = append(args, query.Limit+1)
+
+	rows, err := s.db.Query(s.rebind(stmt), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var records []*TokenRecord
+	for rows.Next() {
+		record, err := scanToken(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var next *TokenCursor
+	if len(records) > query.Limit {
+		records = records[:query.Limit]
+		next = tokenCursorFor(records[len(records)-1], query)
+	}
+	return records, next, nil
+}
+
+// ListTokenTxs 列出关联到代币记录的交易
+func (s *sqlStore) ListTokenTxs(tokenID string) ([]*TxRecord, error) {
+	rows, err := s.db.Query(s.rebind("SELECT "+txColumns+" FROM transactions WHERE token_id = ? ORDER BY submitted_at"), tokenID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txs []*TxRecord
+	for rows.Next() {
+		record, err := scanTx(rows)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, record)
+	}
+	return txs, rows.Err()
+}
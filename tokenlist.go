@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/address"
+)
+
+// 代币列表每页条数
+const (
+	defaultTokenPageSize = 20
+	maxTokenPageSize     = 100
+)
+
+// tokenStatuses 可用于过滤的记录状态
+var tokenStatuses = map[string]bool{
+	TokenStatusCompileFailed: true,
+	TokenStatusCompiled:      true,
+	TokenStatusSubmitted:     true,
+	TokenStatusConfirmed:     true,
+	TokenStatusFailed:        true,
+}
+
+// TokenSummary 列表中的代币记录，不含编译产物
+type TokenSummary struct {
+	ID              string    `json:"id"`
+	Symbol          string    `json:"symbol"`
+	Name            string    `json:"name"`
+	Decimals        int       `json:"decimals"`
+	Network         string    `json:"network,omitempty"`
+	Status          string    `json:"status"`
+	Sender          string    `json:"sender,omitempty"`
+	CompilerVersion string    `json:"compiler_version"`
+	TxDigest        string    `json:"tx_digest,omitempty"`
+	PackageID       string    `json:"package_id,omitempty"`
+	CoinType        string    `json:"coin_type,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+func newTokenSummary(record *TokenRecord) TokenSummary {
+	return TokenSummary{
+		ID:              record.ID,
+		Symbol:          record.Request.Symbol,
+		Name:            record.Request.Name,
+		Decimals:        record.Request.Decimal,
+		Network:         record.Network,
+		Status:          record.Status,
+		Sender:          record.Sender,
+		CompilerVersion: record.CompilerVersion,
+		TxDigest:        record.TxDigest,
+		PackageID:       record.PackageID,
+		CoinType:        record.CoinType,
+		Error:           record.Error,
+		CreatedAt:       record.CreatedAt,
+		UpdatedAt:       record.UpdatedAt,
+	}
+}
+
+// parseTokenQuery 解析列表查询参数：
+// symbol、name（名称前缀）、sender、network、status、from、to（创建时间范围）、
+// sort（created_at、updated_at、symbol、name）、order（asc、desc）、limit、cursor
+func parseTokenQuery(values url.Values) (TokenQuery, error) {
+	query := TokenQuery{
+		Symbol:     values.Get("symbol"),
+		NamePrefix: values.Get("name"),
+		Network:    values.Get("network"),
+		Status:     values.Get("status"),
+		Sort:       TokenSortCreatedAt,
+		Desc:       true,
+		Limit:      defaultTokenPageSize,
+	}
+
+	if sender := values.Get("sender"); sender != "" {
+		addr, err := address.Parse(sender)
+		if err != nil {
+			return query, fmt.Errorf("sender 地址无效: %v", err)
+		}
+		query.Sender = addr.Hex()
+	}
+	if query.Status != "" && !tokenStatuses[query.Status] {
+		return query, fmt.Errorf("不支持的状态: %s", query.Status)
+	}
+
+	var err error
+	if query.CreatedFrom, err = parseTimeParam(values.Get("from"), false); err != nil {
+		return query, fmt.Errorf("from 无效: %v", err)
+	}
+	if query.CreatedTo, err = parseTimeParam(values.Get("to"), true); err != nil {
+		return query, fmt.Errorf("to 无效: %v", err)
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		if _, ok := tokenSortColumns[sort]; !ok {
+			return query, fmt.Errorf("不支持的排序字段: %s", sort)
+		}
+		query.Sort = sort
+	}
+	switch values.Get("order") {
+	case "", "desc":
+	case "asc":
+		query.Desc = false
+	default:
+		return query, fmt.Errorf("order 只能是 asc 或 desc")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return query, fmt.Errorf("limit 必须是正整数")
+		}
+		if n > maxTokenPageSize {
+			n = maxTokenPageSize
+		}
+		query.Limit = n
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		after, err := decodeTokenCursor(cursor)
+		if err != nil {
+			return query, err
+		}
+		if after.Sort != query.Sort || after.Desc != query.Desc {
+			return query, fmt.Errorf("游标与当前排序方式不一致")
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+// parseTimeParam 解析 RFC3339 时间或 UTC 日期（YYYY-MM-DD），
+// endOfDay 为 true 时日期表示当天结束，使 to=2024-01-31 包含当天的记录
+func parseTimeParam(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("时间格式应为 RFC3339 或 YYYY-MM-DD: %s", value)
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// encodeTokenCursor 游标对客户端不透明
+func encodeTokenCursor(cursor *TokenCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTokenCursor(text string) (*TokenCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil {
+		return nil, errors.New("无效的游标")
+	}
+	var cursor TokenCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" {
+		return nil, errors.New("无效的游标")
+	}
+	return &cursor, nil
+}
+
+// listTokens 分页查询代币记录
+func listTokens(w http.ResponseWriter, r *http.Request) {
+	query, err := parseTokenQuery(r.URL.Query())
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	records, next, err := tokenStore.ListTokens(query)
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("查询代币记录失败: %v", err),
+		})
+		return
+	}
+
+	items := make([]TokenSummary, len(records))
+	for i, record := range records {
+		items[i] = newTokenSummary(record)
+	}
+	data := map[string]interface{}{
+		"items":    items,
+		"has_more": next != nil,
+	}
+	if next != nil {
+		data["next_cursor"] = encodeTokenCursor(next)
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "查询代币记录成功",
+		Data:    data,
+	})
+}
+
+// getTokenRecord 查询单条代币记录，包括编译产物、链上对象 ID 和关联交易
+func getTokenRecord(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	record, err := tokenStore.GetToken(id)
+	if errors.Is(err, ErrNotFound) {
+		writeResponse(w, http.StatusNotFound, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("代币记录不存在: %s", id),
+		})
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("查询代币记录失败: %v", err),
+		})
+		return
+	}
+
+	txs, err := tokenStore.ListTokenTxs(id)
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("查询关联交易失败: %v", err),
+		})
+		return
+	}
+	if txs == nil {
+		txs = []*TxRecord{}
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "查询代币记录成功",
+		Data: map[string]interface{}{
+			"token":        record,
+			"transactions": txs,
+		},
+	})
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/address"
+	"obc_coin_api/benfen"
+)
+
+// TokenOpRequest 代币操作交易的通用参数
+type TokenOpRequest struct {
+	Sender              string `json:"sender"`
+	Gas                 string `json:"gas,omitempty"`
+	GasBudget           string `json:"gas_budget"`
+	RebuildWithEstimate bool   `json:"rebuild_with_estimate,omitempty"`
+	// Execute 为 true 时由服务端签名并提交，需要管理令牌
+	Execute bool `json:"execute,omitempty"`
+	// Network 留空时使用默认网络
+	Network string `json:"network,omitempty"`
+}
+
+// MoveCall 一次 Move 函数调用
+type MoveCall struct {
+	Package  string        `json:"package"`
+	Module   string        `json:"module"`
+	Function string        `json:"function"`
+	TypeArgs []string      `json:"type_arguments"`
+	Args     []interface{} `json:"arguments"`
+}
+
+// MetadataUpdateRequest 更新代币元数据请求
+type MetadataUpdateRequest struct {
+	TokenOpRequest
+	// MetadataObjectID 元数据对象 ID，未指定时使用发布记录中的 CoinMetadata
+	MetadataObjectID string          `json:"metadata_object_id,omitempty"`
+	Metadata         json.RawMessage `json:"metadata"`
+}
+
+// batchBuilder 返回调用 unsafe_batchTransaction 将多个 Move 调用构建为一笔交易的函数
+func batchBuilder(sender string, calls []MoveCall) txBuildFunc {
+	params := make([]benfen.BatchTransactionParams, len(calls))
+	for i, call := range calls {
+		params[i].MoveCall = &benfen.MoveCallParams{
+			PackageObjectID: call.Package,
+			Module:          call.Module,
+			Function:        call.Function,
+			TypeArguments:   call.TypeArgs,
+			Arguments:       call.Args,
+		}
+	}
+	return func(ctx context.Context, gas, gasBudget string) (*benfen.TransactionBlockBytes, error) {
+		var tx benfen.TransactionBlockBytes
+		if err := rpcFor(ctx).Call(ctx, benfen.MethodUnsafeBatch, &tx, sender, params, gas, gasBudget); err != nil {
+			return nil, err
+		}
+		return &tx, nil
+	}
+}
+
+// moveCallBuilder 返回调用 unsafe_moveCall 构建交易的函数
+func moveCallBuilder(sender string, call MoveCall) txBuildFunc {
+	return func(ctx context.Context, gas, gasBudget string) (*benfen.TransactionBlockBytes, error) {
+		var tx benfen.TransactionBlockBytes
+		params := []interface{}{sender, call.Package, call.Module, call.Function, call.TypeArgs, call.Args, gas, gasBudget}
+		if err := rpcFor(ctx).Call(ctx, benfen.MethodUnsafeMoveCall, &tx, params...); err != nil {
+			return nil, err
+		}
+		return &tx, nil
+	}
+}
+
+// prepareTokenOp 选择网络、校验通用参数并确定发送者，服务端执行时发送者固定为服务端账户。
+// 返回设置了网络的请求，校验失败时已写出响应并返回 false。
+func prepareTokenOp(w http.ResponseWriter, r *http.Request, op *TokenOpRequest) (*http.Request, bool) {
+	r, ok := selectNetwork(w, r, op.Network)
+	if !ok {
+		return r, false
+	}
+	op.Network = networkFrom(r.Context()).Name
+
+	if op.Execute {
+		if !isAdminRequest(r) {
+			writeResponse(w, http.StatusForbidden, TokenResponse{
+				Success: false,
+				Message: "服务端执行需要管理令牌",
+			})
+			return r, false
+		}
+		if serverSigner == nil {
+			writeResponse(w, http.StatusForbidden, TokenResponse{
+				Success: false,
+				Message: errSignerDisabled.Error(),
+			})
+			return r, false
+		}
+		if op.Sender == "" {
+			op.Sender = serverSigner.Address()
+		}
+	}
+
+	sender, err := address.Parse(op.Sender)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("sender 无效: %v", err),
+		})
+		return r, false
+	}
+	op.Sender = sender.Hex()
+
+	if op.Execute && op.Sender != serverSigner.Address() {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("服务端执行时 sender 必须是服务端账户 %s", serverSigner.Address()),
+		})
+		return r, false
+	}
+
+	if _, err := parseGasBudget(r.Context(), op.GasBudget); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return r, false
+	}
+	return r, true
+}
+
+// runTokenOp 构建代币操作交易，按请求返回未签名交易或由服务端签名执行
+func runTokenOp(w http.ResponseWriter, r *http.Request, op TokenOpRequest, kind, tokenID string, build txBuildFunc, data map[string]interface{}) {
+	opts := TxOptions{
+		Sender:              op.Sender,
+		Gas:                 op.Gas,
+		GasBudget:           op.GasBudget,
+		RebuildWithEstimate: op.RebuildWithEstimate,
+	}
+	built, err := buildTransaction(r.Context(), opts, build)
+	if err != nil {
+		status, response := txBuildErrorResponse("构建交易失败", err)
+		writeResponse(w, status, response)
+		return
+	}
+
+	data["kind"] = kind
+	data["transaction"] = built
+	if !op.Execute {
+		writeResponse(w, http.StatusOK, TokenResponse{
+			Success: true,
+			Message: "构建交易成功",
+			Data:    data,
+		})
+		return
+	}
+
+	result, err := signAndExecute(r.Context(), built, kind, tokenID)
+	if err != nil {
+		writeRPCError(w, "执行交易失败", err)
+		return
+	}
+	data["execution"] = result
+	if result.Status != "success" {
+		writeResponse(w, http.StatusUnprocessableEntity, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("交易执行失败: %s", result.Error),
+			Data:    data,
+		})
+		return
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "交易执行成功",
+		Data:    data,
+	})
+}
+
+// findTokenRecord 在 ctx 所选网络上按代币类型查找发布记录，不存在时返回 nil
+func findTokenRecord(ctx context.Context, coinType string) (*TokenRecord, error) {
+	record, err := tokenStore.FindTokenByCoinType(networkFrom(ctx).Name, coinType)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return record, err
+}
+
+// resolveTreasuryCap 确定并规范化 TreasuryCap 对象 ID，未指定时使用发布记录中的 TreasuryCap，
+// 返回关联的编译记录 ID。校验失败时已写出响应并返回 false。
+func resolveTreasuryCap(ctx context.Context, w http.ResponseWriter, coinType string, treasuryCapID *string) (string, bool) {
+	return resolveTokenObject(ctx, w, coinType, treasuryCapID, "treasury_cap_id", func(record *TokenRecord) string {
+		return record.TreasuryCapID
+	})
+}
+
+// resolveMetadataObject 确定并规范化 CoinMetadata 对象 ID，未指定时使用发布记录中的 CoinMetadata
+func resolveMetadataObject(ctx context.Context, w http.ResponseWriter, coinType string, metadataObjectID *string) (string, bool) {
+	return resolveTokenObject(ctx, w, coinType, metadataObjectID, "metadata_object_id", func(record *TokenRecord) string {
+		return record.CoinMetadataID
+	})
+}
+
+// resolveTokenObject 按代币类型查找发布记录，补全并校验请求中的对象 ID
+func resolveTokenObject(ctx context.Context, w http.ResponseWriter, coinType string, objectID *string, field string, fromRecord func(*TokenRecord) string) (string, bool) {
+	record, err := findTokenRecord(ctx, coinType)
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("查询代币记录失败: %v", err),
+		})
+		return "", false
+	}
+	var tokenID string
+	if record != nil {
+		tokenID = record.ID
+		if *objectID == "" {
+			*objectID = fromRecord(record)
+		}
+	}
+	if *objectID == "" {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("未找到该代币的发布记录，请指定 %s", field),
+		})
+		return "", false
+	}
+	parsed, err := address.Parse(*objectID)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("%s 无效: %v", field, err),
+		})
+		return "", false
+	}
+	*objectID = parsed.Hex()
+	return tokenID, true
+}
+
+// writeCoinMetadataError 返回查询代币元数据失败的响应
+func writeCoinMetadataError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errCoinMetadataNotFound) {
+		writeResponse(w, http.StatusNotFound, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	writeRPCError(w, "查询代币元数据失败", err)
+}
+
+// encodeMetadata 将元数据 JSON 对象压缩后编码为 vector<u8> 参数，保留原有字段顺序
+func encodeMetadata(raw json.RawMessage) ([]int, error) {
+	var object map[string]interface{}
+	if err := json.Unmarshal(raw, &object); err != nil || object == nil {
+		return nil, fmt.Errorf("metadata 必须是 JSON 对象")
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return nil, err
+	}
+	encoded := make([]int, compact.Len())
+	for i, b := range compact.Bytes() {
+		encoded[i] = int(b)
+	}
+	return encoded, nil
+}
+
+// updateTokenMetadata 构建调用 metadata::update_metadata 的交易
+func updateTokenMetadata(w http.ResponseWriter, r *http.Request) {
+	coinType, err := parseCoinType(chi.URLParam(r, "coinType"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	var req MetadataUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+
+	metadata, err := encodeMetadata(req.Metadata)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	r, ok := prepareTokenOp(w, r, &req.TokenOpRequest)
+	if !ok {
+		return
+	}
+
+	tokenID, ok := resolveMetadataObject(r.Context(), w, coinType, &req.MetadataObjectID)
+	if !ok {
+		return
+	}
+
+	call := MoveCall{
+		Package:  networkFrom(r.Context()).MetadataPackage(),
+		Module:   "metadata",
+		Function: "update_metadata",
+		TypeArgs: []string{coinType},
+		Args:     []interface{}{req.MetadataObjectID, metadata},
+	}
+	runTokenOp(w, r, req.TokenOpRequest, TxKindMetadataUpdate, tokenID, moveCallBuilder(req.Sender, call), map[string]interface{}{
+		"coin_type": coinType,
+		"call":      call,
+	})
+}
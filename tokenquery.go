@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/address"
+	"obc_coin_api/benfen"
+)
+
+// 缓存条目超过该数量时写入前先清理过期条目
+const queryCacheSweepSize = 10000
+
+// queryCache 短时缓存链上查询结果，减少看板轮询对节点的压力
+type queryCache struct {
+	mu      sync.Mutex
+	entries map[string]queryCacheEntry
+}
+
+type queryCacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// 全局代币查询缓存
+var tokenQueryCache = &queryCache{entries: make(map[string]queryCacheEntry)}
+
+// get 获取未过期的缓存值
+func (c *queryCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+// set 写入缓存值
+func (c *queryCache) set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= queryCacheSweepSize {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = queryCacheEntry{
+		value:   value,
+		expires: now.Add(time.Duration(GetTokenCacheTTLSeconds()) * time.Second),
+	}
+}
+
+// cached 优先返回缓存值，未命中时调用 fetch 并缓存成功的结果
+func cached[T any](key string, fetch func() (T, error)) (T, error) {
+	if value, ok := tokenQueryCache.get(key); ok {
+		return value.(T), nil
+	}
+	value, err := fetch()
+	if err != nil {
+		return value, err
+	}
+	tokenQueryCache.set(key, value)
+	return value, nil
+}
+
+// AmountView 同时以最小单位和可读格式表示的数量
+type AmountView struct {
+	Base   string `json:"base"`
+	Amount string `json:"amount"`
+}
+
+// newAmountView 按精度格式化数量
+func newAmountView(value uint64, decimals uint8) AmountView {
+	return AmountView{
+		Base:   strconv.FormatUint(value, 10),
+		Amount: formatAmount(value, decimals),
+	}
+}
+
+// cachedCoinMetadata 查询代币元数据，使用短时缓存
+func cachedCoinMetadata(ctx context.Context, coinType string) (*benfen.CoinMetadata, error) {
+	return cached(networkFrom(ctx).Name+":metadata:"+coinType, func() (*benfen.CoinMetadata, error) {
+		return fetchCoinMetadata(ctx, coinType)
+	})
+}
+
+// getTokenInfo 查询链上代币元数据和总供应量
+func getTokenInfo(w http.ResponseWriter, r *http.Request) {
+	coinType, err := parseCoinType(chi.URLParam(r, "coinType"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	r, ok := selectNetwork(w, r, r.URL.Query().Get("network"))
+	if !ok {
+		return
+	}
+
+	metadata, err := cachedCoinMetadata(r.Context(), coinType)
+	if err != nil {
+		writeCoinMetadataError(w, err)
+		return
+	}
+	supply, err := cached(networkFrom(r.Context()).Name+":supply:"+coinType, func() (uint64, error) {
+		return fetchTotalSupply(r.Context(), coinType)
+	})
+	if err != nil {
+		writeRPCError(w, "查询总供应量失败", err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"coin_type":    coinType,
+		"network":      networkFrom(r.Context()).Name,
+		"metadata":     metadata,
+		"total_supply": newAmountView(supply, metadata.Decimals),
+	}
+	if record, err := findTokenRecord(r.Context(), coinType); err == nil && record != nil {
+		data["compile_id"] = record.ID
+		data["treasury_cap_id"] = record.TreasuryCapID
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "查询代币信息成功",
+		Data:    data,
+	})
+}
+
+// getTokenBalance 查询地址持有的代币余额
+func getTokenBalance(w http.ResponseWriter, r *http.Request) {
+	coinType, err := parseCoinType(chi.URLParam(r, "coinType"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	owner, err := address.Parse(chi.URLParam(r, "address"))
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("地址无效: %v", err),
+		})
+		return
+	}
+	r, ok := selectNetwork(w, r, r.URL.Query().Get("network"))
+	if !ok {
+		return
+	}
+
+	metadata, err := cachedCoinMetadata(r.Context(), coinType)
+	if err != nil {
+		writeCoinMetadataError(w, err)
+		return
+	}
+	balance, err := cached(networkFrom(r.Context()).Name+":balance:"+coinType+":"+owner.Hex(), func() (*benfen.Balance, error) {
+		var balance benfen.Balance
+		if err := rpcFor(r.Context()).Call(r.Context(), benfen.MethodGetBalance, &balance, owner.Hex(), coinType); err != nil {
+			return nil, err
+		}
+		return &balance, nil
+	})
+	if err != nil {
+		writeRPCError(w, "查询余额失败", err)
+		return
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "查询余额成功",
+		Data: map[string]interface{}{
+			"coin_type":         coinType,
+			"network":           networkFrom(r.Context()).Name,
+			"address":           owner.Hex(),
+			"decimals":          metadata.Decimals,
+			"coin_object_count": balance.CoinObjectCount,
+			"balance":           newAmountView(uint64(balance.TotalBalance), metadata.Decimals),
+		},
+	})
+}
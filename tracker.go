@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/benfen"
+)
+
+// 轮询退避参数
+const (
+	trackerBaseDelay = time.Second
+	trackerMaxDelay  = 30 * time.Second
+)
+
+// txTracker 在后台轮询已提交交易直到成功、失败或超时
+type txTracker struct {
+	mu     sync.Mutex
+	active map[string]bool
+	// recordMu 保证交易记录的读改写不会相互覆盖
+	recordMu sync.Mutex
+}
+
+// 全局交易跟踪器
+var tracker = &txTracker{active: make(map[string]bool)}
+
+// initTxTracker 从存储中恢复未确认的交易并继续跟踪
+func initTxTracker() {
+	pending, err := tokenStore.ListPendingTx()
+	if err != nil {
+		log.Printf("加载待确认交易失败: %v", err)
+		return
+	}
+	for _, record := range pending {
+		tracker.watch(record.Digest)
+	}
+	if len(pending) > 0 {
+		log.Printf("恢复跟踪 %d 笔待确认交易", len(pending))
+	}
+}
+
+// Track 记录在 ctx 所选网络上新提交的交易并开始跟踪，只有发布交易会更新代币记录并投递发布事件
+func (t *txTracker) Track(ctx context.Context, digest, kind, tokenID, sender string) {
+	network := networkFrom(ctx).Name
+	record := &TxRecord{
+		Digest:  digest,
+		Kind:    kind,
+		Network: network,
+		TokenID: tokenID,
+		Sender:  sender,
+		Status:  TxStatusPending,
+	}
+	if err := tokenStore.SaveTx(record); err != nil {
+		log.Printf("保存交易记录失败 %s: %v", digest, err)
+	}
+	if !isPublishTx(kind) {
+		t.watch(digest)
+		return
+	}
+
+	if tokenID != "" {
+		err := updateToken(tokenID, func(token *TokenRecord) bool {
+			if token.Status == TokenStatusConfirmed {
+				return false
+			}
+			token.Status = TokenStatusSubmitted
+			token.TxDigest = digest
+			if sender != "" {
+				token.Sender = sender
+			}
+			return true
+		})
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("更新代币记录失败 %s: %v", tokenID, err)
+		}
+	}
+
+	emitEvent(EventPublishSubmitted, map[string]interface{}{
+		"digest":     digest,
+		"compile_id": tokenID,
+		"sender":     sender,
+		"network":    network,
+	})
+
+	t.watch(digest)
+}
+
+// Resolve 使用已获得的执行结果直接完成跟踪
+func (t *txTracker) Resolve(digest string, result *ExecutionResult) {
+	if result.Status == "" {
+		return
+	}
+	t.finish(digest, result)
+}
+
+// Fail 交易被节点拒绝时直接标记为失败，不再轮询
+func (t *txTracker) Fail(digest string, err error) {
+	t.finish(digest, &ExecutionResult{
+		Digest: digest,
+		Status: "failure",
+		Error:  err.Error(),
+	})
+}
+
+// watch 启动后台轮询，同一交易只会有一个轮询协程
+func (t *txTracker) watch(digest string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.active[digest] {
+		return
+	}
+	t.active[digest] = true
+	go t.poll(digest)
+}
+
+// poll 按指数退避查询交易，直到得到结果或超时
+func (t *txTracker) poll(digest string) {
+	defer func() {
+		t.mu.Lock()
+		delete(t.active, digest)
+		t.mu.Unlock()
+	}()
+
+	timeout := time.Duration(GetTrackerTimeoutSeconds()) * time.Second
+	delay := trackerBaseDelay
+	for {
+		var timedOut bool
+		var network string
+		pending := t.updatePending(digest, func(record *TxRecord) {
+			network = record.Network
+			if time.Since(record.SubmittedAt) > timeout {
+				record.Status = TxStatusTimeout
+				record.Error = fmt.Sprintf("超过 %v 未确认", timeout)
+				timedOut = true
+				return
+			}
+			record.Attempts++
+		})
+		if timedOut {
+			log.Printf("交易确认超时: %s", digest)
+		}
+		if !pending || timedOut {
+			return
+		}
+
+		ctx := withNetwork(context.Background(), lookupNetwork(network))
+		resp, err := fetchTransaction(ctx, digest)
+		if err == nil && resp.Effects != nil {
+			t.finish(digest, newExecutionResult(resp))
+			return
+		}
+		if err != nil {
+			t.updatePending(digest, func(record *TxRecord) {
+				record.Error = err.Error()
+			})
+		}
+
+		time.Sleep(delay)
+		delay *= 2
+		if delay > trackerMaxDelay {
+			delay = trackerMaxDelay
+		}
+	}
+}
+
+// finish 记录交易最终状态并更新关联的代币记录
+func (t *txTracker) finish(digest string, result *ExecutionResult) {
+	var kind, tokenID, sender, network string
+	pending := t.updatePending(digest, func(record *TxRecord) {
+		if result.Status == "success" {
+			record.Status = TxStatusSuccess
+			record.Error = ""
+		} else {
+			record.Status = TxStatusFailure
+			record.Error = result.Error
+		}
+		// 客户端签名的交易提交时不知道发送者，以节点返回的交易数据为准
+		if record.Sender == "" {
+			record.Sender = result.Sender
+		}
+		kind, tokenID, sender, network = record.Kind, record.TokenID, record.Sender, record.Network
+	})
+	if !pending {
+		return
+	}
+	log.Printf("交易已确认: %s (%s)", digest, result.Status)
+	if !isPublishTx(kind) {
+		return
+	}
+
+	recordExecution(tokenID, sender, result)
+
+	event := EventPublishConfirmed
+	if result.Status != "success" {
+		event = EventPublishFailed
+	}
+	emitEvent(event, map[string]interface{}{
+		"digest":     digest,
+		"compile_id": tokenID,
+		"network":    network,
+		"status":     result.Status,
+		"error":      result.Error,
+		"published":  result.Published,
+	})
+}
+
+// isPublishTx 判断是否为发布交易，旧记录没有类型时视为发布交易
+func isPublishTx(kind string) bool {
+	return kind == "" || kind == TxKindPublish
+}
+
+// updatePending 在交易仍处于待确认状态时修改并保存记录，返回修改前是否为待确认
+func (t *txTracker) updatePending(digest string, update func(record *TxRecord)) bool {
+	t.recordMu.Lock()
+	defer t.recordMu.Unlock()
+
+	record, err := tokenStore.GetTx(digest)
+	if err != nil {
+		log.Printf("读取交易记录失败 %s: %v", digest, err)
+		return false
+	}
+	if record.Status != TxStatusPending {
+		return false
+	}
+
+	update(record)
+	if err := tokenStore.SaveTx(record); err != nil {
+		log.Printf("保存交易记录失败 %s: %v", digest, err)
+	}
+	return true
+}
+
+// fetchTransaction 查询交易效果和对象变更
+func fetchTransaction(ctx context.Context, digest string) (*benfen.TransactionBlockResponse, error) {
+	options := benfen.TransactionBlockResponseOptions{
+		ShowInput:         true,
+		ShowEffects:       true,
+		ShowObjectChanges: true,
+	}
+
+	var resp benfen.TransactionBlockResponse
+	if err := rpcFor(ctx).Call(ctx, benfen.MethodGetTransaction, &resp, digest, options); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// getTransactionStatus 查询已提交交易的确认状态
+func getTransactionStatus(w http.ResponseWriter, r *http.Request) {
+	digest := chi.URLParam(r, "digest")
+
+	record, err := tokenStore.GetTx(digest)
+	if errors.Is(err, ErrNotFound) {
+		writeResponse(w, http.StatusNotFound, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("交易不存在: %s", digest),
+		})
+		return
+	}
+	if err != nil {
+		writeResponse(w, http.StatusInternalServerError, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("查询交易失败: %v", err),
+		})
+		return
+	}
+
+	// 编译记录包含发送者和全部请求参数，只有携带管理令牌时返回完整记录
+	data := map[string]interface{}{"transaction": record}
+	if record.TokenID != "" {
+		if token, err := tokenStore.GetToken(record.TokenID); err == nil && isAdminRequest(r) {
+			data["token"] = token
+		} else if err == nil {
+			data["token"] = map[string]interface{}{
+				"compile_id": token.ID,
+				"status":     token.Status,
+				"package_id": token.PackageID,
+				"coin_type":  token.CoinType,
+			}
+		}
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "查询交易状态成功",
+		Data:    data,
+	})
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"obc_coin_api/benfen"
+)
+
+// executeTransaction 提交已签名的交易，并按确认级别等待交易效果
+func executeTransaction(ctx context.Context, txBytes string, signatures []string, requestType string) (*benfen.TransactionBlockResponse, error) {
+	options := benfen.TransactionBlockResponseOptions{
+		ShowInput:         true,
+		ShowEffects:       true,
+		ShowObjectChanges: true,
+	}
+
+	var resp benfen.TransactionBlockResponse
+	if err := rpcFor(ctx).Call(ctx, benfen.MethodExecute, &resp, txBytes, signatures, options, requestType); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ExecutionResult 交易执行结果
+type ExecutionResult struct {
+	Digest                  string                 `json:"digest"`
+	Sender                  string                 `json:"sender,omitempty"`
+	Status                  string                 `json:"status"`
+	Error                   string                 `json:"error,omitempty"`
+	GasUsed                 *benfen.GasCostSummary `json:"gas_used,omitempty"`
+	Published               *PublishedObjects      `json:"published,omitempty"`
+	ObjectChanges           []benfen.ObjectChange  `json:"object_changes,omitempty"`
+	ConfirmedLocalExecution *bool                  `json:"confirmed_local_execution,omitempty"`
+}
+
+// newExecutionResult 由节点返回的交易结果生成执行结果
+func newExecutionResult(resp *benfen.TransactionBlockResponse) *ExecutionResult {
+	result := &ExecutionResult{
+		Digest:                  resp.Digest,
+		Published:               extractPublishedObjects(resp.ObjectChanges),
+		ObjectChanges:           resp.ObjectChanges,
+		ConfirmedLocalExecution: resp.ConfirmedLocalExecution,
+	}
+	if resp.Transaction != nil {
+		result.Sender = resp.Transaction.Data.Sender
+	}
+	if resp.Effects != nil {
+		result.Status = resp.Effects.Status.Status
+		result.Error = resp.Effects.Status.Error
+		result.GasUsed = &resp.Effects.GasUsed
+	}
+	return result
+}
+
+// 序列化签名的标识字节及对应长度（flag || 签名 || 公钥）
+var signatureSchemes = map[byte]struct {
+	name   string
+	length int
+}{
+	0x00: {"ed25519", 1 + 64 + 32},
+	0x01: {"secp256k1", 1 + 64 + 33},
+	0x02: {"secp256r1", 1 + 64 + 33},
+	0x03: {"multisig", 0},
+}
+
+// ExecuteRequest 定义提交已签名交易的请求结构
+type ExecuteRequest struct {
+	TxBytes     string   `json:"tx_bytes"`
+	Signatures  []string `json:"signatures"`
+	RequestType string   `json:"request_type,omitempty"`
+	// 交易类型，默认为 publish，其他类型不会更新编译记录
+	Kind string `json:"kind,omitempty"`
+	// Network 留空时使用编译记录的网络，没有编译记录时使用默认网络
+	Network string `json:"network,omitempty"`
+	// 对应 /api/token/add 返回的 compile_id，用于记录发布结果
+	CompileID string `json:"compile_id,omitempty"`
+}
+
+// validateSignature 校验 base64 编码的序列化签名格式
+func validateSignature(signature string) error {
+	raw, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("签名不是有效的 base64: %v", err)
+	}
+	if len(raw) == 0 {
+		return fmt.Errorf("签名不能为空")
+	}
+	scheme, ok := signatureSchemes[raw[0]]
+	if !ok {
+		return fmt.Errorf("不支持的签名方案标识: 0x%02x", raw[0])
+	}
+	// 多签长度不固定，只要求有内容
+	if scheme.length == 0 {
+		if len(raw) < 2 {
+			return fmt.Errorf("%s 签名内容为空", scheme.name)
+		}
+		return nil
+	}
+	if len(raw) != scheme.length {
+		return fmt.Errorf("%s 签名长度应为 %d 字节，实际 %d", scheme.name, scheme.length, len(raw))
+	}
+	return nil
+}
+
+// executeSignedTransaction 处理提交钱包签名交易的请求
+func executeSignedTransaction(w http.ResponseWriter, r *http.Request) {
+	var req ExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "无效的请求格式",
+		})
+		return
+	}
+
+	if _, err := base64.StdEncoding.DecodeString(req.TxBytes); err != nil || req.TxBytes == "" {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "tx_bytes 必须是有效的 base64 编码",
+		})
+		return
+	}
+	if len(req.Signatures) == 0 {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: "signatures 不能为空",
+		})
+		return
+	}
+	for i, signature := range req.Signatures {
+		if err := validateSignature(signature); err != nil {
+			writeResponse(w, http.StatusBadRequest, TokenResponse{
+				Success: false,
+				Message: fmt.Sprintf("第 %d 个签名无效: %v", i+1, err),
+			})
+			return
+		}
+	}
+
+	switch req.RequestType {
+	case "":
+		req.RequestType = benfen.WaitForLocalExecution
+	case benfen.WaitForEffectsCert, benfen.WaitForLocalExecution:
+	default:
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("request_type 只能是 %s 或 %s", benfen.WaitForEffectsCert, benfen.WaitForLocalExecution),
+		})
+		return
+	}
+
+	switch req.Kind {
+	case "":
+		req.Kind = TxKindPublish
+	case TxKindPublish, TxKindMetadataUpdate, TxKindMint, TxKindBurn, TxKindCapTransfer, TxKindMetadataFreeze:
+	default:
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("不支持的交易类型: %s", req.Kind),
+		})
+		return
+	}
+
+	// 发布交易要求编译记录可以发布且模块一致，其他交易只要求记录存在（通常是已发布的代币）
+	if req.CompileID != "" && isPublishTx(req.Kind) {
+		record, status, err := publishableRecord(req.CompileID)
+		if err == nil {
+			status, err = http.StatusConflict, verifyPublishedModules(req.TxBytes, record)
+		}
+		if err != nil {
+			writeResponse(w, status, TokenResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+			return
+		}
+	} else if req.CompileID != "" {
+		if _, err := tokenStore.GetToken(req.CompileID); err != nil {
+			writeResponse(w, http.StatusNotFound, TokenResponse{
+				Success: false,
+				Message: fmt.Sprintf("编译记录不存在: %s", req.CompileID),
+			})
+			return
+		}
+	}
+
+	r, ok := selectCompileNetwork(w, r, req.Network, req.CompileID)
+	if !ok {
+		return
+	}
+
+	// 提交前记录交易摘要，即使提交超时也能在后台确认最终状态
+	digest, err := benfen.TransactionDigest(req.TxBytes)
+	if err != nil {
+		writeResponse(w, http.StatusBadRequest, TokenResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+		return
+	}
+	tracker.Track(r.Context(), digest, req.Kind, req.CompileID, "")
+
+	resp, err := executeTransaction(r.Context(), req.TxBytes, req.Signatures, req.RequestType)
+	if err != nil {
+		var rpcErr *benfen.RPCError
+		if errors.As(err, &rpcErr) {
+			tracker.Fail(digest, err)
+		}
+		writeRPCError(w, "执行交易失败", err)
+		return
+	}
+
+	result := newExecutionResult(resp)
+	tracker.Resolve(digest, result)
+	if result.Status != "success" {
+		writeResponse(w, http.StatusUnprocessableEntity, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("交易执行失败: %s", result.Error),
+			Data:    result,
+		})
+		return
+	}
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "交易执行成功",
+		Data:    result,
+	})
+}
+
+// PublishedObjects 发布交易创建的包和代币对象
+type PublishedObjects struct {
+	PackageID      string `json:"package_id"`
+	TreasuryCapID  string `json:"treasury_cap_id,omitempty"`
+	CoinMetadataID string `json:"coin_metadata_id,omitempty"`
+	CoinType       string `json:"coin_type,omitempty"`
+}
+
+// extractPublishedObjects 从对象变更中提取包 ID、TreasuryCap 和 CoinMetadata，非发布交易返回 nil
+func extractPublishedObjects(changes []benfen.ObjectChange) *PublishedObjects {
+	var published *PublishedObjects
+	for _, change := range changes {
+		if change.Type == "published" {
+			published = &PublishedObjects{PackageID: change.PackageID}
+		}
+	}
+	if published == nil {
+		return nil
+	}
+
+	for _, change := range changes {
+		if change.Type != "created" {
+			continue
+		}
+		if coinType, ok := coinTypeArgument(change.ObjectType, "::coin::TreasuryCap<"); ok {
+			published.TreasuryCapID = change.ObjectID
+			published.CoinType = normalizeCoinType(coinType)
+		} else if coinType, ok := coinTypeArgument(change.ObjectType, "::coin::CoinMetadata<"); ok {
+			published.CoinMetadataID = change.ObjectID
+			published.CoinType = normalizeCoinType(coinType)
+		}
+	}
+	return published
+}
+
+// coinTypeArgument 若对象类型为 0x2::coin::X<T> 则返回 T
+func coinTypeArgument(objectType, marker string) (string, bool) {
+	idx := strings.Index(objectType, marker)
+	if idx == -1 || !strings.HasSuffix(objectType, ">") {
+		return "", false
+	}
+	return objectType[idx+len(marker) : len(objectType)-1], true
+}
+
+// publishableRecord 读取可以用于发布的编译记录，编译失败或已发布的记录返回 409
+func publishableRecord(compileID string) (*TokenRecord, int, error) {
+	record, err := tokenStore.GetToken(compileID)
+	if err != nil {
+		return nil, http.StatusNotFound, fmt.Errorf("编译记录不存在: %s", compileID)
+	}
+	switch record.Status {
+	case TokenStatusCompileFailed:
+		return nil, http.StatusConflict, fmt.Errorf("编译记录 %s 编译失败，不能发布", compileID)
+	case TokenStatusConfirmed:
+		return nil, http.StatusConflict, fmt.Errorf("编译记录 %s 已由交易 %s 发布", compileID, record.TxDigest)
+	}
+	return record, http.StatusOK, nil
+}
+
+// verifyPublishedModules 校验交易字节中包含编译记录的全部模块。
+// 发布交易以 BCS vector<u8> 编码模块（ULEB128 长度 + 字节码），按同样的编码查找。
+func verifyPublishedModules(txBytes string, record *TokenRecord) error {
+	tx, err := base64.StdEncoding.DecodeString(txBytes)
+	if err != nil {
+		return fmt.Errorf("无效的交易字节: %v", err)
+	}
+	if len(record.Modules) == 0 {
+		return fmt.Errorf("编译记录 %s 没有模块", record.ID)
+	}
+	for i, module := range record.Modules {
+		code, err := base64.StdEncoding.DecodeString(module)
+		if err != nil {
+			return fmt.Errorf("编译记录 %s 的第 %d 个模块无效: %v", record.ID, i+1, err)
+		}
+		if !bytes.Contains(tx, appendULEB128(nil, uint64(len(code)), code...)) {
+			return fmt.Errorf("交易发布的模块与编译记录 %s 不一致", record.ID)
+		}
+	}
+	return nil
+}
+
+// appendULEB128 追加 ULEB128 编码的 n 和之后的字节
+func appendULEB128(dst []byte, n uint64, rest ...byte) []byte {
+	for n >= 0x80 {
+		dst = append(dst, byte(n)|0x80)
+		n >>= 7
+	}
+	dst = append(dst, byte(n))
+	return append(dst, rest...)
+}
+
+// recordExecution 将执行结果记录到对应的编译记录中
+func recordExecution(compileID, sender string, result *ExecutionResult) {
+	if compileID == "" {
+		return
+	}
+	err := updateToken(compileID, func(record *TokenRecord) bool {
+		// 已由其他交易发布的记录不再被覆盖
+		if record.Status == TokenStatusConfirmed && record.TxDigest != result.Digest {
+			log.Printf("编译记录 %s 已由交易 %s 发布，忽略交易 %s 的结果", compileID, record.TxDigest, result.Digest)
+			return false
+		}
+
+		record.TxDigest = result.Digest
+		if sender != "" {
+			record.Sender = sender
+		}
+		if result.Status == "success" {
+			record.Status = TokenStatusConfirmed
+			record.Error = ""
+		} else {
+			record.Status = TokenStatusFailed
+			record.Error = result.Error
+		}
+		if published := result.Published; published != nil {
+			record.PackageID = published.PackageID
+			record.TreasuryCapID = published.TreasuryCapID
+			record.CoinMetadataID = published.CoinMetadataID
+			record.CoinType = published.CoinType
+		}
+		return true
+	})
+	if err != nil {
+		log.Printf("更新代币记录失败 %s: %v", compileID, err)
+	}
+}
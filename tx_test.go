@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"obc_coin_api/benfen"
+	"obc_coin_api/mockrpc"
+)
+
+const testSender = "0x00000000000000000000000000000000000000000000000000000000000000aa"
+
+// useMockNetwork 将默认网络替换为模拟节点，返回指向模拟节点的客户端
+func useMockNetwork(t *testing.T) *benfen.Client {
+	t.Helper()
+	server, err := mockrpc.New(mockrpc.Options{Accounts: map[string]uint64{testSender: 100000000000}})
+	if err != nil {
+		t.Fatalf("创建模拟节点失败: %v", err)
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	client := benfen.NewClient(benfen.Options{URLs: []string{ts.URL}, Timeout: 5 * time.Second})
+
+	savedConfig, savedNetworks := AppConfig, networks
+	t.Cleanup(func() { AppConfig, networks = savedConfig, savedNetworks })
+	AppConfig = &Config{DefaultNetwork: "devnet"}
+	networks = map[string]*Network{"devnet": {Name: "devnet", Client: client}}
+	return client
+}
+
+// useFileStore 将代币记录存储替换为临时文件，测试结束前等待后台跟踪的交易结束
+func useFileStore(t *testing.T) {
+	t.Helper()
+	store, err := openFileStore(filepath.Join(t.TempDir(), "tokens.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := tokenStore
+	tokenStore = store
+	t.Cleanup(func() {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			tracker.mu.Lock()
+			idle := len(tracker.active) == 0
+			tracker.mu.Unlock()
+			if idle {
+				break
+			}
+		}
+		tokenStore = saved
+	})
+}
+
+// 已发布代币的铸造交易携带编译记录 ID 时不应因记录已确认而被拒绝，也不改变编译记录
+func TestExecuteMintWithConfirmedCompileID(t *testing.T) {
+	client := useMockNetwork(t)
+	useFileStore(t)
+	ctx := context.Background()
+	signature := base64.StdEncoding.EncodeToString(make([]byte, 97))
+
+	var built benfen.TransactionBlockBytes
+	module := base64.StdEncoding.EncodeToString([]byte("fast_coin bytecode"))
+	if err := client.Call(ctx, benfen.MethodUnsafePublish, &built,
+		testSender, []string{module}, []string{"0x1", "0x2"}, "", "10000000"); err != nil {
+		t.Fatalf("构建发布交易失败: %v", err)
+	}
+	published, err := executeTransaction(withNetwork(ctx, networks["devnet"]), built.TxBytes, []string{signature}, benfen.WaitForLocalExecution)
+	if err != nil {
+		t.Fatalf("执行发布交易失败: %v", err)
+	}
+	objects := extractPublishedObjects(published.ObjectChanges)
+	if objects == nil || objects.TreasuryCapID == "" {
+		t.Fatalf("发布交易没有创建 TreasuryCap: %+v", published.ObjectChanges)
+	}
+
+	record := &TokenRecord{
+		ID:            newID(),
+		Network:       "devnet",
+		Status:        TokenStatusConfirmed,
+		TxDigest:      published.Digest,
+		PackageID:     objects.PackageID,
+		TreasuryCapID: objects.TreasuryCapID,
+		CoinType:      objects.CoinType,
+	}
+	if err := tokenStore.SaveToken(record); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Call(ctx, benfen.MethodUnsafeMoveCall, &built, testSender, "0x2", "coin", "mint_and_transfer",
+		[]string{objects.CoinType}, []interface{}{objects.TreasuryCapID, "1000", testSender}, "", "10000000"); err != nil {
+		t.Fatalf("构建铸造交易失败: %v", err)
+	}
+	body, _ := json.Marshal(ExecuteRequest{
+		TxBytes:    built.TxBytes,
+		Signatures: []string{signature},
+		Kind:       TxKindMint,
+		CompileID:  record.ID,
+	})
+	rec := httptest.NewRecorder()
+	executeSignedTransaction(rec, httptest.NewRequest(http.MethodPost, "/api/tx/execute", bytes.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码为 %d，应为 200: %s", rec.Code, rec.Body.String())
+	}
+
+	after, err := tokenStore.GetToken(record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Status != TokenStatusConfirmed || after.TxDigest != published.Digest {
+		t.Fatalf("铸造交易不应改变编译记录: status=%s tx_digest=%s", after.Status, after.TxDigest)
+	}
+
+	// 不存在的编译记录仍然返回 404
+	body, _ = json.Marshal(ExecuteRequest{
+		TxBytes:    built.TxBytes,
+		Signatures: []string{signature},
+		Kind:       TxKindMint,
+		CompileID:  "missing",
+	})
+	rec = httptest.NewRecorder()
+	executeSignedTransaction(rec, httptest.NewRequest(http.MethodPost, "/api/tx/execute", bytes.NewReader(body)))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "missing") {
+		t.Fatalf("不存在的编译记录应返回 404，实际 %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+// 同一编译记录的并发更新串行执行，不会相互覆盖，结束后不保留锁
+func TestUpdateTokenSerialized(t *testing.T) {
+	useFileStore(t)
+	record := &TokenRecord{ID: newID(), Status: TokenStatusCompiled}
+	if err := tokenStore.SaveToken(record); err != nil {
+		t.Fatal(err)
+	}
+
+	const updates = 50
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := updateToken(record.ID, func(token *TokenRecord) bool {
+				token.Modules = append(token.Modules, "m")
+				return true
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	after, err := tokenStore.GetToken(record.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after.Modules) != updates {
+		t.Fatalf("并发更新后有 %d 个模块，应为 %d", len(after.Modules), updates)
+	}
+	tokenLocks.Lock()
+	defer tokenLocks.Unlock()
+	if len(tokenLocks.locks) != 0 {
+		t.Fatalf("更新结束后仍保留 %d 把锁", len(tokenLocks.locks))
+	}
+}
+
+// 未携带管理令牌时交易状态只返回编译记录的公开字段
+func TestTransactionStatusHidesTokenRecord(t *testing.T) {
+	useFileStore(t)
+	savedConfig := AppConfig
+	t.Cleanup(func() { AppConfig = savedConfig })
+	AppConfig = &Config{}
+	AppConfig.Admin.Token = "admin-token"
+
+	token := &TokenRecord{ID: newID(), Status: TokenStatusConfirmed, Sender: testSender, CoinType: "0x1::a::A"}
+	if err := tokenStore.SaveToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if err := tokenStore.SaveTx(&TxRecord{Digest: "digest", Kind: TxKindPublish, TokenID: token.ID, Status: TxStatusSuccess}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := chi.NewRouter()
+	router.Get("/api/tx/{digest}/status", getTransactionStatus)
+	for _, tc := range []struct {
+		name       string
+		adminToken string
+		wantSender bool
+	}{
+		{"匿名", "", false},
+		{"管理令牌", "admin-token", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/tx/digest/status", nil)
+			if tc.adminToken != "" {
+				req.Header.Set("X-Admin-Token", tc.adminToken)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			var resp struct {
+				Data struct {
+					Token map[string]interface{} `json:"token"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("解析响应失败: %v", err)
+			}
+			if resp.Data.Token["coin_type"] != token.CoinType {
+				t.Fatalf("响应中缺少编译记录的 coin_type: %s", rec.Body.String())
+			}
+			if _, ok := resp.Data.Token["sender"]; ok != tc.wantSender {
+				t.Fatalf("编译记录是否包含 sender 应为 %v: %s", tc.wantSender, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"obc_coin_api/benfen"
+)
+
+// TxOptions 构建交易的通用参数
+type TxOptions struct {
+	Sender              string
+	Gas                 string
+	GasBudget           string
+	RebuildWithEstimate bool
+}
+
+// BuiltTransaction 定义未签名交易的构建结果
+type BuiltTransaction struct {
+	Network      string             `json:"network"`
+	Sender       string             `json:"sender"`
+	TxBytes      string             `json:"tx_bytes"`
+	Gas          []benfen.ObjectRef `json:"gas"`
+	InputObjects []json.RawMessage  `json:"input_objects"`
+	GasBudget    string             `json:"gas_budget"`
+	GasCoin      *GasCoinSelection  `json:"gas_coin,omitempty"`
+	GasEstimate  *GasEstimate       `json:"gas_estimate,omitempty"`
+	DryRunError  string             `json:"dry_run_error,omitempty"`
+	Rebuilt      bool               `json:"rebuilt,omitempty"`
+}
+
+// txBuildFunc 使用给定的 gas 币和预算调用 unsafe_* 方法构建交易
+type txBuildFunc func(ctx context.Context, gas, gasBudget string) (*benfen.TransactionBlockBytes, error)
+
+// GasEstimate 预执行得到的 gas 估算
+type GasEstimate struct {
+	ComputationCost         uint64 `json:"computation_cost,string"`
+	StorageCost             uint64 `json:"storage_cost,string"`
+	StorageRebate           uint64 `json:"storage_rebate,string"`
+	NonRefundableStorageFee uint64 `json:"non_refundable_storage_fee,string"`
+	RecommendedBudget       uint64 `json:"recommended_budget,string"`
+	// ProbeBudget 原预算不足时重新预执行使用的预算，此时用量和推荐预算来自重新预执行
+	ProbeBudget uint64 `json:"probe_budget,string,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+// parseGasBudget 解析 gas 预算，并校验不超过当前网络的上限
+func parseGasBudget(ctx context.Context, text string) (uint64, error) {
+	budget, err := strconv.ParseUint(text, 10, 64)
+	if err != nil || budget == 0 {
+		return 0, fmt.Errorf("gas_budget 必须是正整数")
+	}
+	network := networkFrom(ctx)
+	if max := network.MaxGasBudget(); max > 0 && budget > max {
+		return 0, fmt.Errorf("gas_budget 不能超过网络 %s 的上限 %d", network.Name, max)
+	}
+	return budget, nil
+}
+
+// buildTransaction 构建未签名交易，并按配置预执行估算 gas。
+// 未指定 gas 币时自动选择发送者的 BFC 币。
+func buildTransaction(ctx context.Context, opts TxOptions, build txBuildFunc) (*BuiltTransaction, error) {
+	var gasCoin *GasCoinSelection
+	if opts.Gas == "" {
+		budget, err := parseGasBudget(ctx, opts.GasBudget)
+		if err != nil {
+			return nil, err
+		}
+		coin, err := selectGasCoin(ctx, opts.Sender, budget)
+		if err != nil {
+			return nil, err
+		}
+		opts.Gas = coin.CoinObjectID
+		gasCoin = &GasCoinSelection{
+			ObjectID:     coin.CoinObjectID,
+			Balance:      uint64(coin.Balance),
+			AutoSelected: true,
+		}
+	}
+
+	tx, err := build(ctx, opts.Gas, opts.GasBudget)
+	if err != nil {
+		if gasCoin != nil {
+			gasCoinLocks.release(gasCoin.ObjectID)
+		}
+		return nil, err
+	}
+	result := &BuiltTransaction{
+		Network:      networkFrom(ctx).Name,
+		Sender:       opts.Sender,
+		TxBytes:      tx.TxBytes,
+		Gas:          tx.Gas,
+		InputObjects: tx.InputObjects,
+		GasBudget:    opts.GasBudget,
+		GasCoin:      gasCoin,
+	}
+
+	if !IsGasDryRunEnabled() {
+		return result, nil
+	}
+
+	// 预执行失败不影响交易返回，只记录错误
+	estimate, err := estimateGas(ctx, tx.TxBytes)
+	if err != nil {
+		log.Printf("预执行交易失败: %v", err)
+		result.DryRunError = err.Error()
+		return result, nil
+	}
+	// 预算不足时预执行在 gas 耗尽处中止，用量被预算截断，按可用的最大预算重新估算
+	if isInsufficientGas(estimate) {
+		probeGasEstimate(ctx, opts, gasCoin, build, estimate)
+	}
+	result.GasEstimate = estimate
+
+	// 按推荐预算重新构建交易
+	if opts.RebuildWithEstimate && (estimate.Status == "success" || estimate.ProbeBudget > 0) {
+		recommended := estimate.RecommendedBudget
+		if max := networkFrom(ctx).MaxGasBudget(); max > 0 && recommended > max {
+			recommended = max
+		}
+		if err := ensureGasCoinBalance(ctx, &opts, result, recommended); err != nil {
+			return nil, err
+		}
+		gasBudget := strconv.FormatUint(recommended, 10)
+		rebuilt, err := build(ctx, opts.Gas, gasBudget)
+		if err != nil {
+			if result.GasCoin != nil {
+				gasCoinLocks.release(result.GasCoin.ObjectID)
+			}
+			return nil, fmt.Errorf("按推荐预算重新构建交易失败: %w", err)
+		}
+		result.TxBytes = rebuilt.TxBytes
+		result.Gas = rebuilt.Gas
+		result.InputObjects = rebuilt.InputObjects
+		result.GasBudget = gasBudget
+		result.Rebuilt = true
+	}
+
+	return result, nil
+}
+
+// isInsufficientGas 判断预执行是否因预算不足失败
+func isInsufficientGas(estimate *GasEstimate) bool {
+	return estimate.Status != "success" && strings.Contains(estimate.Error, "InsufficientGas")
+}
+
+// probeGasEstimate 使用 gas 币余额（不超过网络上限）构建并预执行交易，成功时用得到的用量和推荐预算替换估算。
+// 自动选择 gas 币时使用发送者余额最大的 BFC 币探测，客户端指定时使用指定的币。探测失败只记录日志，保留原估算
+func probeGasEstimate(ctx context.Context, opts TxOptions, gasCoin *GasCoinSelection, build txBuildFunc, estimate *GasEstimate) {
+	var probeBudget uint64
+	if gasCoin != nil {
+		coins, err := fetchAllCoins(ctx, opts.Sender, benfen.BFCCoinType)
+		if err != nil {
+			log.Printf("查询 gas 币失败，无法重新估算: %v", err)
+			return
+		}
+		for _, coin := range coins {
+			if uint64(coin.Balance) > probeBudget {
+				opts.Gas, probeBudget = coin.CoinObjectID, uint64(coin.Balance)
+			}
+		}
+	} else {
+		balance, err := gasCoinBalance(ctx, opts.Sender, opts.Gas)
+		if err != nil {
+			log.Printf("查询 gas 币余额失败，无法重新估算: %v", err)
+			return
+		}
+		probeBudget = balance
+	}
+	if max := networkFrom(ctx).MaxGasBudget(); max > 0 && probeBudget > max {
+		probeBudget = max
+	}
+	current, _ := strconv.ParseUint(opts.GasBudget, 10, 64)
+	if probeBudget <= current {
+		return
+	}
+
+	tx, err := build(ctx, opts.Gas, strconv.FormatUint(probeBudget, 10))
+	if err != nil {
+		log.Printf("按预算 %d 构建探测交易失败: %v", probeBudget, err)
+		return
+	}
+	probed, err := estimateGas(ctx, tx.TxBytes)
+	if err != nil {
+		log.Printf("按预算 %d 重新预执行失败: %v", probeBudget, err)
+		return
+	}
+	if probed.Status != "success" {
+		log.Printf("按预算 %d 重新预执行仍未成功: %s", probeBudget, probed.Error)
+		return
+	}
+	estimate.ComputationCost = probed.ComputationCost
+	estimate.StorageCost = probed.StorageCost
+	estimate.StorageRebate = probed.StorageRebate
+	estimate.NonRefundableStorageFee = probed.NonRefundableStorageFee
+	estimate.RecommendedBudget = probed.RecommendedBudget
+	estimate.ProbeBudget = probeBudget
+}
+
+// ensureGasCoinBalance 确认 gas 币余额足以支付新预算。
+// 自动选择的币不足时释放并按新预算重新选择，客户端指定的币不足时返回余额不足错误
+func ensureGasCoinBalance(ctx context.Context, opts *TxOptions, result *BuiltTransaction, budget uint64) error {
+	if result.GasCoin == nil {
+		balance, err := gasCoinBalance(ctx, opts.Sender, opts.Gas)
+		if err != nil {
+			return fmt.Errorf("查询 gas 币余额失败: %w", err)
+		}
+		if balance < budget {
+			return &GasSelectionError{
+				Message: fmt.Sprintf("gas 币 %s 余额 %d 不足以支付推荐预算 %d", opts.Gas, balance, budget),
+			}
+		}
+		return nil
+	}
+	if result.GasCoin.Balance >= budget {
+		return nil
+	}
+
+	gasCoinLocks.release(result.GasCoin.ObjectID)
+	result.GasCoin = nil
+	coin, err := selectGasCoin(ctx, opts.Sender, budget)
+	if err != nil {
+		return err
+	}
+	opts.Gas = coin.CoinObjectID
+	result.GasCoin = &GasCoinSelection{
+		ObjectID:     coin.CoinObjectID,
+		Balance:      uint64(coin.Balance),
+		AutoSelected: true,
+	}
+	return nil
+}
+
+// estimateGas 预执行交易并计算推荐 gas 预算
+func estimateGas(ctx context.Context, txBytes string) (*GasEstimate, error) {
+	var dryRun benfen.DryRunResult
+	if err := rpcFor(ctx).Call(ctx, benfen.MethodDryRun, &dryRun, txBytes); err != nil {
+		return nil, err
+	}
+
+	gasUsed := dryRun.Effects.GasUsed
+	estimate := &GasEstimate{
+		ComputationCost:         uint64(gasUsed.ComputationCost),
+		StorageCost:             uint64(gasUsed.StorageCost),
+		StorageRebate:           uint64(gasUsed.StorageRebate),
+		NonRefundableStorageFee: uint64(gasUsed.NonRefundableStorageFee),
+		Status:                  dryRun.Effects.Status.Status,
+		Error:                   dryRun.Effects.Status.Error,
+	}
+
+	// 预算需覆盖返还前的计算和存储费用，再按配置上浮
+	cost := estimate.ComputationCost + estimate.StorageCost
+	estimate.RecommendedBudget = cost + cost*uint64(GetGasBudgetMarginPercent())/100
+
+	return estimate, nil
+}
+
+// txBuildErrorResponse 生成交易构建失败对应的 HTTP 状态码和响应，gas 币选择失败时附带合并方案
+func txBuildErrorResponse(message string, err error) (int, TokenResponse) {
+	var gasErr *GasSelectionError
+	if errors.As(err, &gasErr) {
+		status := http.StatusUnprocessableEntity
+		response := TokenResponse{
+			Success: false,
+			Message: gasErr.Message,
+		}
+		if gasErr.Plan != nil {
+			status = http.StatusConflict
+			response.Data = map[string]interface{}{"merge_plan": gasErr.Plan}
+		}
+		return status, response
+	}
+	return rpcErrorResponse(message, err)
+}
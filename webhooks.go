@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// 代币生命周期事件
+const (
+	EventCompileSucceeded = "token.compile.succeeded"
+	EventCompileFailed    = "token.compile.failed"
+	EventPublishSubmitted = "token.publish.submitted"
+	EventPublishConfirmed = "token.publish.confirmed"
+	EventPublishFailed    = "token.publish.failed"
+	EventCleanupRemoved   = "workspace.cleanup.removed"
+)
+
+// webhook 投递参数
+const (
+	webhookBaseDelay     = 2 * time.Second
+	webhookMaxDelay      = 5 * time.Minute
+	webhookLogSize       = 500
+	webhookDeadLetterMax = 1000
+)
+
+// WebhookEvent 投递给订阅方的事件
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery 一次事件到一个订阅地址的投递
+type WebhookDelivery struct {
+	ID        string    `json:"id"`
+	EventID   string    `json:"event_id"`
+	EventType string    `json:"event_type"`
+	URL       string    `json:"url"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	payload []byte
+	secret  string
+}
+
+// WebhookAttempt 投递日志中的一次尝试
+type WebhookAttempt struct {
+	DeliveryID string    `json:"delivery_id"`
+	EventType  string    `json:"event_type"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Time       time.Time `json:"time"`
+}
+
+// webhookDispatcher 异步投递 webhook，记录投递日志和死信
+type webhookDispatcher struct {
+	mu          sync.Mutex
+	attempts    []WebhookAttempt
+	deadLetters []*WebhookDelivery
+}
+
+// 全局 webhook 分发器
+var webhooks = &webhookDispatcher{}
+
+// emitEvent 向所有订阅了该事件的地址异步投递
+func emitEvent(eventType string, data interface{}) {
+	subscriptions := GetWebhookSubscriptions()
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	event := WebhookEvent{
+		ID:        newID(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("序列化 webhook 事件失败 %s: %v", eventType, err)
+		return
+	}
+
+	for _, sub := range subscriptions {
+		if !subscribesTo(sub, eventType) {
+			continue
+		}
+		delivery := &WebhookDelivery{
+			ID:        newID(),
+			EventID:   event.ID,
+			EventType: eventType,
+			URL:       sub.URL,
+			CreatedAt: event.CreatedAt,
+			payload:   payload,
+			secret:    sub.Secret,
+		}
+		go webhooks.deliver(delivery)
+	}
+}
+
+// subscribesTo 判断订阅是否包含该事件，未指定事件时订阅全部
+func subscribesTo(sub WebhookSubscription, eventType string) bool {
+	if len(sub.Events) == 0 {
+		return true
+	}
+	for _, event := range sub.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver 按指数退避投递，超过最大次数后放入死信列表
+func (d *webhookDispatcher) deliver(delivery *WebhookDelivery) {
+	client := &http.Client{Timeout: time.Duration(GetWebhookTimeoutSeconds()) * time.Second}
+	maxAttempts := GetWebhookMaxAttempts()
+	delay := webhookBaseDelay
+
+	for delivery.Attempts < maxAttempts {
+		delivery.Attempts++
+		err := d.send(client, delivery)
+		if err == nil {
+			return
+		}
+		delivery.LastError = err.Error()
+
+		if delivery.Attempts < maxAttempts {
+			time.Sleep(delay)
+			delay *= 2
+			if delay > webhookMaxDelay {
+				delay = webhookMaxDelay
+			}
+		}
+	}
+
+	log.Printf("webhook 投递失败，已放入死信列表: %s %s (%s)", delivery.EventType, delivery.URL, delivery.LastError)
+	d.mu.Lock()
+	d.deadLetters = append(d.deadLetters, delivery)
+	if len(d.deadLetters) > webhookDeadLetterMax {
+		d.deadLetters = d.deadLetters[len(d.deadLetters)-webhookDeadLetterMax:]
+	}
+	d.mu.Unlock()
+}
+
+// send 发送一次带签名的投递请求，非 2xx 响应视为失败
+func (d *webhookDispatcher) send(client *http.Client, delivery *WebhookDelivery) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		d.logAttempt(delivery, 0, err, 0)
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-OBC-Event", delivery.EventType)
+	req.Header.Set("X-OBC-Delivery", delivery.ID)
+	req.Header.Set("X-OBC-Timestamp", timestamp)
+	req.Header.Set("X-OBC-Signature", "sha256="+signWebhook(delivery.secret, timestamp, delivery.payload))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		d.logAttempt(delivery, 0, err, duration)
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err = fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	d.logAttempt(delivery, resp.StatusCode, err, duration)
+	return err
+}
+
+// signWebhook 计算签名: hex(HMAC-SHA256(secret, timestamp + "." + payload))
+func signWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// logAttempt 记录投递日志，只保留最近的记录
+func (d *webhookDispatcher) logAttempt(delivery *WebhookDelivery, statusCode int, err error, duration time.Duration) {
+	attempt := WebhookAttempt{
+		DeliveryID: delivery.ID,
+		EventType:  delivery.EventType,
+		URL:        delivery.URL,
+		Attempt:    delivery.Attempts,
+		StatusCode: statusCode,
+		DurationMs: duration.Milliseconds(),
+		Time:       time.Now(),
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.attempts = append(d.attempts, attempt)
+	if len(d.attempts) > webhookLogSize {
+		d.attempts = d.attempts[len(d.attempts)-webhookLogSize:]
+	}
+}
+
+// retryDeadLetter 将死信移出列表并重新投递
+func (d *webhookDispatcher) retryDeadLetter(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i, delivery := range d.deadLetters {
+		if delivery.ID == id {
+			d.deadLetters = append(d.deadLetters[:i], d.deadLetters[i+1:]...)
+			delivery.Attempts = 0
+			go d.deliver(delivery)
+			return true
+		}
+	}
+	return false
+}
+
+// webhookDeliveries 返回最近的投递日志，最新的在前
+func webhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	webhooks.mu.Lock()
+	attempts := make([]WebhookAttempt, len(webhooks.attempts))
+	for i, attempt := range webhooks.attempts {
+		attempts[len(attempts)-1-i] = attempt
+	}
+	webhooks.mu.Unlock()
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "获取投递日志成功",
+		Data: map[string]interface{}{
+			"subscriptions": GetWebhookSubscriptions(),
+			"attempts":      attempts,
+		},
+	})
+}
+
+// webhookDeadLetters 返回死信列表
+func webhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	webhooks.mu.Lock()
+	deadLetters := make([]WebhookDelivery, len(webhooks.deadLetters))
+	for i, delivery := range webhooks.deadLetters {
+		deadLetters[i] = *delivery
+	}
+	webhooks.mu.Unlock()
+
+	writeResponse(w, http.StatusOK, TokenResponse{
+		Success: true,
+		Message: "获取死信列表成功",
+		Data:    deadLetters,
+	})
+}
+
+// retryWebhookDeadLetter 重新投递一条死信
+func retryWebhookDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if !webhooks.retryDeadLetter(id) {
+		writeResponse(w, http.StatusNotFound, TokenResponse{
+			Success: false,
+			Message: fmt.Sprintf("死信不存在: %s", id),
+		})
+		return
+	}
+
+	writeResponse(w, http.StatusAccepted, TokenResponse{
+		Success: true,
+		Message: "已重新投递",
+	})
+}